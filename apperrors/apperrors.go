@@ -0,0 +1,102 @@
+package apperrors
+
+import (
+	"errors"
+)
+
+// Kind classifies an error by how it should be reported to the client
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindNotFound
+	KindConflict
+	KindForbidden
+	KindValidation
+	KindUpstream
+)
+
+var kindNames = map[Kind]string{
+	KindInternal:   "internal",
+	KindNotFound:   "not found",
+	KindConflict:   "conflict",
+	KindForbidden:  "forbidden",
+	KindValidation: "validation",
+	KindUpstream:   "upstream",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Error is an error that is safe to classify for the client. Msg is shown to the client for
+// every kind except Internal and Upstream; the wrapped error is only ever logged.
+type Error struct {
+	Kind Kind
+	Msg  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, msg string) error {
+	return &Error{Kind: kind, Msg: msg}
+}
+
+func Wrap(kind Kind, err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Msg: msg, Err: err}
+}
+
+func NotFound(msg string) error {
+	return New(KindNotFound, msg)
+}
+
+func Conflict(msg string) error {
+	return New(KindConflict, msg)
+}
+
+func Forbidden(msg string) error {
+	return New(KindForbidden, msg)
+}
+
+func Validation(msg string) error {
+	return New(KindValidation, msg)
+}
+
+func Upstream(err error, msg string) error {
+	return Wrap(KindUpstream, err, msg)
+}
+
+func Internal(err error, msg string) error {
+	return Wrap(KindInternal, err, msg)
+}
+
+// KindOf reports the kind of err; errors that were never classified are Internal
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// Message returns the client-facing message of err, or an empty string if it has none
+func Message(err error) string {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Msg
+	}
+	return ""
+}
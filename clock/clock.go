@@ -0,0 +1,105 @@
+package clock
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/metabloxStaking/settings"
+)
+
+const ModeReal = "real"
+const ModeFake = "fake"
+
+// Clock is the source of the current time for all time-dependent business logic: maturity
+// checks, closure windows, interest accrual and the background jobs.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock runs at the speed of the real clock but shifted by an offset that can be moved
+// forward, so that a whole staging environment can be fast-forwarded to e.g. the end of a term.
+// The offset is shared by every instance through the database and synced into each one.
+type FakeClock struct {
+	mu     sync.RWMutex
+	offset time.Duration
+}
+
+func NewFakeClock(offset time.Duration) *FakeClock {
+	return &FakeClock{offset: offset}
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return time.Now().Add(f.offset)
+}
+
+func (f *FakeClock) Offset() time.Duration {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.offset
+}
+
+// Sync moves the clock to the shared offset. An offset behind the clock's own is ignored, since
+// orders, accruals and transactions already recorded at the later time would end up in the future.
+func (f *FakeClock) Sync(offset time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if offset > f.offset {
+		f.offset = offset
+	}
+}
+
+var current Clock = realClock{}
+
+// Init selects the clock from config. The fake clock is refused in production, where it would
+// shift maturity, closure windows and interest. Its offset starts at clock.offset until it is
+// synced with the shared one.
+func Init() error {
+	mode := viper.GetString("clock.mode")
+	switch mode {
+	case "", ModeReal:
+		current = realClock{}
+	case ModeFake:
+		if settings.Environment() == "prod" {
+			return errors.New("the fake clock cannot be used in prod")
+		}
+		offset := viper.GetDuration("clock.offset")
+		if offset < 0 {
+			return errors.New("clock.offset must not be negative")
+		}
+		current = NewFakeClock(offset)
+		logger.Warn("using fake clock with offset " + offset.String())
+	default:
+		return errors.New("unknown clock mode " + mode)
+	}
+	return nil
+}
+
+func Set(c Clock) {
+	current = c
+}
+
+// Fake returns the fake clock if one is in use
+func Fake() (*FakeClock, bool) {
+	fake, ok := current.(*FakeClock)
+	return fake, ok
+}
+
+func Now() time.Time {
+	return current.Now()
+}
+
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestFakeClockSync(t *testing.T) {
+	tests := []struct {
+		name   string
+		start  time.Duration
+		shared time.Duration
+		want   time.Duration
+	}{
+		{"moves forward to the shared offset", time.Hour, 3 * time.Hour, 3 * time.Hour},
+		{"already in step", time.Hour, time.Hour, time.Hour},
+		{"never moves back", 3 * time.Hour, time.Hour, 3 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeClock(tt.start)
+			fake.Sync(tt.shared)
+			if got := fake.Offset(); got != tt.want {
+				t.Errorf("Offset() = %v, want %v", got, tt.want)
+			}
+			if drift := time.Until(fake.Now()) - tt.want; drift < -time.Second || drift > time.Second {
+				t.Errorf("Now() is %v away from the offset", drift)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		mode     string
+		wantErr  bool
+		wantFake bool
+	}{
+		{"real clock in prod", "prod", ModeReal, false, false},
+		{"fake clock in prod", "prod", ModeFake, true, false},
+		{"fake clock in staging", "staging", ModeFake, false, true},
+		{"fake clock without an environment", "", ModeFake, false, true},
+		{"unknown mode", "dev", "slow", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("env", tt.env)
+			viper.Set("clock.mode", tt.mode)
+			defer viper.Reset()
+			Set(realClock{})
+			defer Set(realClock{})
+
+			err := Init()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, fake := Fake(); fake != tt.wantFake {
+				t.Errorf("fake clock in use = %v, want %v", fake, tt.wantFake)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Command reconcile compares the staking contract's Transfer events with the transactions recorded
+// in the database and reports missing, duplicate and mismatched entries.
+//
+//	go run ./cmd/reconcile -from 1000 -to 2000 -format csv -tickets
+//
+// Without -from, the most recent -lookback blocks up to -to are scanned.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/reconcile"
+	"github.com/metabloxStaking/settings"
+)
+
+const defaultLookback = 10000
+
+func main() {
+	fromBlock := flag.Uint64("from", 0, "first block to scan; defaults to -lookback blocks before -to")
+	toBlock := flag.Uint64("to", 0, "last block to scan; defaults to the latest block")
+	lookback := flag.Uint64("lookback", defaultLookback, "number of blocks to scan when -from is not given")
+	format := flag.String("format", "json", "report format, json or csv")
+	tickets := flag.Bool("tickets", false, "open a remediation ticket for each new finding")
+	configPath := flag.String("config", "", "path to the config file; defaults to $METABLOX_CONFIG or ./config.yaml")
+	flag.Parse()
+
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintln(os.Stderr, "format must be json or csv")
+		os.Exit(2)
+	}
+
+	if *lookback == 0 {
+		fmt.Fprintln(os.Stderr, "lookback must be at least 1")
+		os.Exit(2)
+	}
+
+	err := run(*configPath, *fromBlock, *toBlock, *lookback, *format, *tickets)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string, fromBlock, toBlock, lookback uint64, format string, tickets bool) error {
+	err := settings.Init(configPath)
+	if err != nil {
+		return err
+	}
+	err = clock.Init()
+	if err != nil {
+		return err
+	}
+	err = contract.Init()
+	if err != nil {
+		return err
+	}
+	err = dao.InitSql()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	err = dao.SyncFakeClock(ctx)
+	if err != nil {
+		return err
+	}
+	if toBlock == 0 {
+		toBlock, err = contract.LatestBlock(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if fromBlock == 0 {
+		fromBlock = recentStart(toBlock, lookback)
+	}
+
+	report, err := reconcile.Run(ctx, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+
+	if format == "csv" {
+		err = reconcile.WriteCSV(os.Stdout, report)
+	} else {
+		err = reconcile.WriteJSON(os.Stdout, report)
+	}
+	if err != nil {
+		return err
+	}
+
+	if tickets {
+		opened, err := reconcile.OpenTickets(ctx, report)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "opened %d remediation tickets\n", opened)
+	}
+	return nil
+}
+
+// recentStart returns the first of the last lookback blocks up to toBlock
+func recentStart(toBlock, lookback uint64) uint64 {
+	if toBlock < lookback {
+		return 0
+	}
+	return toBlock - lookback + 1
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestRecentStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		toBlock  uint64
+		lookback uint64
+		want     uint64
+	}{
+		{"long chain", 50000, 10000, 40001},
+		{"chain exactly as long as the lookback", 10000, 10000, 1},
+		{"chain shorter than the lookback", 500, 10000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recentStart(tt.toBlock, tt.lookback); got != tt.want {
+				t.Errorf("recentStart(%d, %d) = %d, want %d", tt.toBlock, tt.lookback, got, tt.want)
+			}
+		})
+	}
+}
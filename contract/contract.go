@@ -3,19 +3,33 @@ package contract
 import (
 	"context"
 	"crypto/ecdsa"
-	"fmt"
+	"errors"
 	"math/big"
+	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/logging"
+	"github.com/metabloxStaking/metrics"
+	"github.com/metabloxStaking/settings"
 	"github.com/metabloxStaking/stakingContract"
+	"github.com/metabloxStaking/tracing"
 )
 
 const deployedContract = "0xc70A4185af369cfF34507Fe14b651fbEe53fed88"
 const network = "wss://ws.s0.b.hmny.io"
 
+const defaultCallTimeout = 10 * time.Second
+const defaultTransferTimeout = time.Minute
+
 var client *ethclient.Client
 
 var instance *stakingContract.StakingContract
@@ -27,12 +41,12 @@ func Init() error {
 	var err error
 	client, err = ethclient.Dial(network)
 	if err != nil {
-		return err
+		return apperrors.Upstream(err, "failed to connect to chain")
 	}
 	contractAddress = common.HexToAddress(deployedContract)
 	instance, err = stakingContract.NewStakingContract(contractAddress, client)
 	if err != nil {
-		return err
+		return apperrors.Internal(err, "failed to bind staking contract")
 	}
 
 	ownerKey, _ = crypto.HexToECDSA("dbbd9634560466ac9713e0cf10a575456c8b55388bce0c044f33fc6074dc5ae6")
@@ -40,19 +54,27 @@ func Init() error {
 	return nil
 }
 
-func generateAuth(privateKey *ecdsa.PrivateKey) (*bind.TransactOpts, error) {
+func generateAuth(ctx context.Context, privateKey *ecdsa.PrivateKey) (*bind.TransactOpts, error) {
 	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, big.NewInt(1666700000))
 	if err != nil {
-		return nil, err
+		return nil, apperrors.Internal(err, "failed to create transactor")
 	}
-	authNonce, err := client.PendingNonceAt(context.Background(), crypto.PubkeyToAddress(privateKey.PublicKey))
+	var authNonce uint64
+	err = call(ctx, "PendingNonceAt", func(ctx context.Context) (err error) {
+		authNonce, err = client.PendingNonceAt(ctx, crypto.PubkeyToAddress(privateKey.PublicKey))
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, apperrors.Upstream(err, "failed to get pending nonce")
 	}
 
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	var gasPrice *big.Int
+	err = call(ctx, "SuggestGasPrice", func(ctx context.Context) (err error) {
+		gasPrice, err = client.SuggestGasPrice(ctx)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, apperrors.Upstream(err, "failed to get gas price")
 	}
 	auth.Nonce = big.NewInt(int64(authNonce))
 	auth.Value = big.NewInt(0)
@@ -61,7 +83,7 @@ func generateAuth(privateKey *ecdsa.PrivateKey) (*bind.TransactOpts, error) {
 	return auth, nil
 }
 
-func TransferTokens(toAddress common.Address, value int) error {
+func TransferTokens(ctx context.Context, toAddress common.Address, value int) error {
 	//balance, err := instance.TokenBalance(nil)
 	//if err != nil {
 	//	return err
@@ -69,28 +91,126 @@ func TransferTokens(toAddress common.Address, value int) error {
 
 	bigValue := big.NewInt(int64(value))
 
-	auth, err := generateAuth(ownerKey)
+	auth, err := generateAuth(ctx, ownerKey)
 	if err != nil {
 		return err
 	}
 
-	tx, err := instance.Transfer(auth, toAddress, bigValue)
+	var tx *types.Transaction
+	err = callWithin(ctx, "Transfer", configuredTimeout(settings.Current().Contract.TransferTimeout, defaultTransferTimeout), func(ctx context.Context) (err error) {
+		auth.Context = ctx
+		tx, err = instance.Transfer(auth, toAddress, bigValue)
+		return err
+	})
 	if err != nil {
-		return nil
+		return apperrors.Upstream(err, "failed to send transfer")
 	}
 
-	fmt.Println("tx address: ", tx.Hash().Hex())
+	logging.FromContext(ctx).WithField("tx_hash", tx.Hash().Hex()).Info("sent token transfer")
 	return nil
 }
 
-func CheckIfTransactionCompleted(txHash string) (bool, error) { //todo: full implementation
-	return true, nil
+// confirmedReceipt returns the receipt of the transaction once it has succeeded and been buried
+// under the number of blocks set by contract.confirmations, or nil until then
+func confirmedReceipt(ctx context.Context, txHash string) (*types.Receipt, error) {
+	if !Connected() {
+		return nil, apperrors.New(apperrors.KindUpstream, "not connected to the chain")
+	}
+
+	var receipt *types.Receipt
+	err := call(ctx, "TransactionReceipt", func(ctx context.Context) (err error) {
+		receipt, err = client.TransactionReceipt(ctx, common.HexToHash(txHash))
+		return err
+	})
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, apperrors.Upstream(err, "failed to get transaction receipt")
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return nil, nil
+	}
+
+	latest, err := LatestBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mined := receipt.BlockNumber.Uint64()
+	if latest < mined {
+		//the node serving this call is behind the one that returned the receipt
+		return nil, nil
+	}
+	if latest-mined+1 < settings.Current().Confirmations {
+		return nil, nil
+	}
+	return receipt, nil
 }
 
-func RedeemOrder() string { //todo: full implementation
+func RedeemOrder(ctx context.Context) string { //todo: full implementation
 	return "placeholderHash"
 }
 
-func RedeemInterest() string { //todo: full implementation
+func RedeemInterest(ctx context.Context) string { //todo: full implementation
 	return "placeholderHash"
 }
+
+// TokenBalance returns the contract's MBLX balance
+func TokenBalance(ctx context.Context) (float64, error) {
+	if instance == nil {
+		return 0, apperrors.New(apperrors.KindInternal, "staking contract is not initialized")
+	}
+	var balance *big.Int
+	err := call(ctx, "TokenBalance", func(ctx context.Context) (err error) {
+		balance, err = instance.TokenBalance(&bind.CallOpts{Context: ctx})
+		return err
+	})
+	if err != nil {
+		return 0, apperrors.Upstream(err, "failed to get contract token balance")
+	}
+	return TokensFromWei(balance), nil
+}
+
+// Connected reports whether Init has connected to the chain
+func Connected() bool {
+	return client != nil
+}
+
+// Close disconnects from the chain
+func Close() {
+	if client != nil {
+		client.Close()
+	}
+}
+
+// call runs a chain RPC bounded by contract.callTimeout
+func call(ctx context.Context, method string, rpc func(ctx context.Context) error) error {
+	return callWithin(ctx, method, configuredTimeout(settings.Current().Contract.CallTimeout, defaultCallTimeout), rpc)
+}
+
+// callWithin runs a chain RPC bounded by timeout, tracing and timing it, and logging it against the
+// request if it fails
+func callWithin(ctx context.Context, method string, timeout time.Duration, rpc func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "contract."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCSystemKey.String("jsonrpc"), semconv.RPCMethodKey.String(method)),
+	)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := metrics.ObserveChainCall(method, func() error {
+		err := rpc(ctx)
+		if err != nil {
+			logging.FromContext(ctx).WithField("rpc_method", method).Warn("chain call failed: " + err.Error())
+		}
+		return err
+	})
+	tracing.End(span, err)
+	return err
+}
+
+func configuredTimeout(timeout, fallback time.Duration) time.Duration {
+	if timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
@@ -0,0 +1,157 @@
+package contract
+
+import (
+	"context"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+	"github.com/metabloxStaking/stakingContract"
+)
+
+const defaultTokenDecimals = 18
+
+// LatestBlock returns the number of the most recent block
+func LatestBlock(ctx context.Context) (uint64, error) {
+	var number uint64
+	err := call(ctx, "BlockNumber", func(ctx context.Context) (err error) {
+		number, err = client.BlockNumber(ctx)
+		return err
+	})
+	if err != nil {
+		return 0, apperrors.Upstream(err, "failed to get latest block")
+	}
+	return number, nil
+}
+
+// BlockTime returns the timestamp of the given block
+func BlockTime(ctx context.Context, number uint64) (time.Time, error) {
+	var header *types.Header
+	err := call(ctx, "HeaderByNumber", func(ctx context.Context) (err error) {
+		header, err = client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		return err
+	})
+	if err != nil {
+		return time.Time{}, apperrors.Upstream(err, "failed to get block header")
+	}
+	return time.Unix(int64(header.Time), 0).UTC(), nil
+}
+
+// FilterTransfers returns every Transfer event emitted by the staking contract between the given
+// blocks, inclusive
+func FilterTransfers(ctx context.Context, fromBlock, toBlock uint64) ([]*models.ChainTransfer, error) {
+	var iterator *stakingContract.StakingContractTransferIterator
+	err := call(ctx, "FilterTransfer", func(ctx context.Context) (err error) {
+		opts := &bind.FilterOpts{
+			Start:   fromBlock,
+			End:     &toBlock,
+			Context: ctx,
+		}
+		iterator, err = instance.FilterTransfer(opts, nil, nil)
+		return err
+	})
+	if err != nil {
+		return nil, apperrors.Upstream(err, "failed to filter transfer events")
+	}
+	defer iterator.Close()
+
+	var transfers []*models.ChainTransfer
+	for iterator.Next() {
+		event := iterator.Event
+		transfer := models.NewChainTransfer()
+		transfer.TXHash = event.Raw.TxHash.Hex()
+		transfer.From = event.From.Hex()
+		transfer.To = event.To.Hex()
+		transfer.Amount = TokensFromWei(event.Value)
+		transfer.BlockNumber = event.Raw.BlockNumber
+		transfer.LogIndex = event.Raw.Index
+		transfers = append(transfers, transfer)
+	}
+	if iterator.Error() != nil {
+		return nil, apperrors.Upstream(iterator.Error(), "failed to read transfer events")
+	}
+	return transfers, nil
+}
+
+// transferEvent is the topic of the staking contract's Transfer event
+var transferEvent = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// ConfirmedDeposit returns the amount, in wei, that the transaction moved from the given address
+// into the staking contract, or nil until the transaction has succeeded and been buried under
+// contract.confirmations blocks
+func ConfirmedDeposit(ctx context.Context, txHash, from string) (*big.Int, error) {
+	receipt, err := confirmedReceipt(ctx, txHash)
+	if err != nil || receipt == nil {
+		return nil, err
+	}
+	return depositedBy(receipt.Logs, contractAddress, common.HexToAddress(from)), nil
+}
+
+// depositedBy totals the Transfer events the contract emitted in logs that move tokens from the
+// sender to the contract itself
+func depositedBy(logs []*types.Log, contract, sender common.Address) *big.Int {
+	total := new(big.Int)
+	for _, log := range logs {
+		if log.Removed || log.Address != contract || len(log.Topics) != 3 || log.Topics[0] != transferEvent || len(log.Data) != 32 {
+			continue
+		}
+		if common.BytesToAddress(log.Topics[1].Bytes()) != sender || common.BytesToAddress(log.Topics[2].Bytes()) != contract {
+			continue
+		}
+		total.Add(total, new(big.Int).SetBytes(log.Data))
+	}
+	return total
+}
+
+func tokenDecimals() int64 {
+	decimals := settings.Current().Contract.TokenDecimals
+	if decimals <= 0 {
+		decimals = defaultTokenDecimals
+	}
+	return decimals
+}
+
+// TokensFromWei converts an on-chain token amount to MBLX using contract.tokenDecimals
+func TokensFromWei(value *big.Int) float64 {
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(tokenDecimals()), nil))
+	tokens, _ := new(big.Float).Quo(new(big.Float).SetInt(value), scale).Float64()
+	return tokens
+}
+
+// WeiFromTokens converts an MBLX amount to on-chain token units using contract.tokenDecimals. The
+// amount is read as its shortest decimal form, so 0.1 MBLX is exactly 10^17 wei at 18 decimals.
+// It returns false if the amount has more decimal places than the token.
+func WeiFromTokens(tokens float64) (*big.Int, bool) {
+	amount, ok := new(big.Rat).SetString(strconv.FormatFloat(tokens, 'f', -1, 64))
+	if !ok {
+		return nil, false
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(tokenDecimals()), nil)
+	amount.Mul(amount, new(big.Rat).SetInt(scale))
+	if !amount.IsInt() {
+		return nil, false
+	}
+	return new(big.Int).Set(amount.Num()), true
+}
+
+// LatestBlockAge returns how long ago the most recent block was produced. A node that has stopped
+// syncing keeps answering but its latest block grows old.
+func LatestBlockAge(ctx context.Context) (time.Duration, error) {
+	var header *types.Header
+	err := call(ctx, "HeaderByNumber", func(ctx context.Context) (err error) {
+		header, err = client.HeaderByNumber(ctx, nil)
+		return err
+	})
+	if err != nil {
+		return 0, apperrors.Upstream(err, "failed to get latest block header")
+	}
+	return time.Since(time.Unix(int64(header.Time), 0)), nil
+}
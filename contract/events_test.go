@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestDepositedBy(t *testing.T) {
+	stakingAddress := common.HexToAddress("0xc70A4185af369cfF34507Fe14b651fbEe53fed88")
+	user := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	transfer := func(emitter, from, to common.Address, value int64) *types.Log {
+		return &types.Log{
+			Address: emitter,
+			Topics:  []common.Hash{transferEvent, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+			Data:    common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+		}
+	}
+	removed := transfer(stakingAddress, user, stakingAddress, 5)
+	removed.Removed = true
+	otherEvent := transfer(stakingAddress, user, stakingAddress, 5)
+	otherEvent.Topics[0] = common.HexToHash("0x01")
+
+	tests := []struct {
+		name string
+		logs []*types.Log
+		want int64
+	}{
+		{"no logs", nil, 0},
+		{"deposit", []*types.Log{transfer(stakingAddress, user, stakingAddress, 100)}, 100},
+		{"several deposits are summed", []*types.Log{transfer(stakingAddress, user, stakingAddress, 100), transfer(stakingAddress, user, stakingAddress, 50)}, 150},
+		{"from another sender", []*types.Log{transfer(stakingAddress, other, stakingAddress, 100)}, 0},
+		{"to another recipient", []*types.Log{transfer(stakingAddress, user, other, 100)}, 0},
+		{"emitted by another contract", []*types.Log{transfer(other, user, stakingAddress, 100)}, 0},
+		{"removed by a reorg", []*types.Log{removed}, 0},
+		{"another event", []*types.Log{otherEvent}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := depositedBy(tt.logs, stakingAddress, user)
+			if got.Cmp(big.NewInt(tt.want)) != 0 {
+				t.Errorf("depositedBy() = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeiFromTokens(t *testing.T) {
+	tests := []struct {
+		tokens float64
+		want   string
+		ok     bool
+	}{
+		{1, "1000000000000000000", true},
+		{0.1, "100000000000000000", true},
+		{1234.5678, "1234567800000000000000", true},
+		{1e-18, "1", true},
+		{1e-19, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := WeiFromTokens(tt.tokens)
+		if ok != tt.ok {
+			t.Errorf("WeiFromTokens(%v) ok = %v, want %v", tt.tokens, ok, tt.ok)
+			continue
+		}
+		if ok && got.String() != tt.want {
+			t.Errorf("WeiFromTokens(%v) = %s, want %s", tt.tokens, got, tt.want)
+		}
+		if ok && TokensFromWei(got) != tt.tokens {
+			t.Errorf("TokensFromWei(WeiFromTokens(%v)) = %v", tt.tokens, TokensFromWei(got))
+		}
+	}
+}
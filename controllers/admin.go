@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/products"
+)
+
+// OperatorContextKey holds the name of the authenticated operator
+const OperatorContextKey = "operator"
+
+// OperatorRoleContextKey holds the role of the authenticated operator
+const OperatorRoleContextKey = "operatorRole"
+
+func operatorName(c *gin.Context) string {
+	return c.GetString(OperatorContextKey)
+}
+
+func CreateProductHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	input := models.NewProductInput()
+	if !bindJSON(c, input) {
+		return
+	}
+	err := validateProductInput(input)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	product := models.NewStakingProduct()
+	applyProductInput(product, input)
+	product.State = products.InitialState(product)
+
+	productID, err := dao.CreateProduct(ctx, product, operatorName(c))
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	product, err = dao.GetStakingProductByID(ctx, productID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, product)
+}
+
+func UpdateProductHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	input := models.NewProductInput()
+	if !bindJSON(c, input) {
+		return
+	}
+	err := validateProductInput(input)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	old, err := dao.GetStakingProductByID(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	if old.Retired || old.State == models.ProductStateSettled {
+		ResponseErr(c, apperrors.Conflict("product has been retired and can no longer be changed"))
+		return
+	}
+
+	updated := *old
+	applyProductInput(&updated, input)
+	err = dao.UpdateProduct(ctx, &updated, validateProductUpdate, operatorName(c))
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	//a new start date or top-up limit can change the scheduled state
+	err = products.Refresh(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	product, err := dao.GetStakingProductByID(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, product)
+}
+
+func PauseProductHandler(c *gin.Context) {
+	changeProductStatus(c, models.ProductActionPause)
+}
+
+func ResumeProductHandler(c *gin.Context) {
+	changeProductStatus(c, models.ProductActionResume)
+}
+
+func RetireProductHandler(c *gin.Context) {
+	changeProductStatus(c, models.ProductActionRetire)
+}
+
+func GetProductHistoryHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+
+	_, err := dao.GetStakingProductByID(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	history, err := dao.GetProductHistory(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, history)
+}
+
+func changeProductStatus(c *gin.Context, action string) {
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+
+	old, err := dao.GetStakingProductByID(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	updated, err := products.Transition(ctx, old, action)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	err = dao.SetProductState(ctx, old, updated, action, operatorName(c))
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	//a retired product with no open orders can settle straight away
+	err = products.Refresh(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	product, err := dao.GetStakingProductByID(ctx, params.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, product)
+}
+
+func validateProductInput(input *models.ProductInput) error {
+	if input.StartDate.IsZero() {
+		return apperrors.Validation("start date is required")
+	}
+	if input.LockUpPeriod > input.Term {
+		return apperrors.Validation("lock-up period cannot be longer than the term")
+	}
+	return nil
+}
+
+// validateProductUpdate rejects changes that would retroactively worsen the terms of orders that
+// are already in the product, whether still pending, holding or matured. Term and lock-up only
+// apply to new buy-ins and renewals, since each order keeps the dates computed when it was bought.
+// The minimum order value is checked again when a pending order is bought in, so it cannot be
+// raised while users may already have paid for an order under the old minimum.
+func validateProductUpdate(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error {
+	if !old.StartDate.IsZero() && !clock.Now().Before(old.StartDate.Time) && !updated.StartDate.Equal(old.StartDate.Time) {
+		return apperrors.Conflict("start date cannot be changed once the product has started")
+	}
+	if liveOrders == 0 {
+		return nil
+	}
+	if updated.DefaultAPY < old.DefaultAPY {
+		return apperrors.Conflict("APY cannot be lowered while orders are in the product")
+	}
+	if updated.MinRedeemValue > old.MinRedeemValue {
+		return apperrors.Conflict("minimum redeem value cannot be raised while orders are in the product")
+	}
+	if pendingOrders > 0 && updated.MinOrderValue > old.MinOrderValue {
+		return apperrors.Conflict("minimum order value cannot be raised while orders are pending")
+	}
+	if updated.TopUpLimit < totalPrincipal {
+		return apperrors.Conflict("top-up limit cannot be lowered below the principal already staked")
+	}
+	return nil
+}
+
+func applyProductInput(product *models.StakingProduct, input *models.ProductInput) {
+	product.ProductName = input.ProductName
+	product.MinOrderValue = input.MinOrderValue
+	product.TopUpLimit = input.TopUpLimit
+	product.MinRedeemValue = input.MinRedeemValue
+	product.LockUpPeriod = input.LockUpPeriod
+	product.Term = input.Term
+	product.DefaultAPY = input.DefaultAPY
+	product.StartDate = input.StartDate
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/models"
+)
+
+func TestValidateProductUpdate(t *testing.T) {
+	started := &models.StakingProduct{
+		ID:             "1",
+		MinOrderValue:  10,
+		TopUpLimit:     1000,
+		MinRedeemValue: 5,
+		DefaultAPY:     0.1,
+		StartDate:      models.NewTimestamp(clock.Now().Add(-24 * time.Hour)),
+	}
+	change := func(apply func(p *models.StakingProduct)) *models.StakingProduct {
+		updated := *started
+		apply(&updated)
+		return &updated
+	}
+
+	tests := []struct {
+		name           string
+		updated        *models.StakingProduct
+		liveOrders     int
+		pendingOrders  int
+		totalPrincipal float64
+		wantErr        bool
+	}{
+		{"start date moved after starting", change(func(p *models.StakingProduct) { p.StartDate = models.NewTimestamp(clock.Now().Add(time.Hour)) }), 0, 0, 0, true},
+		{"APY lowered without orders", change(func(p *models.StakingProduct) { p.DefaultAPY = 0.05 }), 0, 0, 0, false},
+		{"APY lowered with a pending, holding or matured order", change(func(p *models.StakingProduct) { p.DefaultAPY = 0.05 }), 1, 0, 0, true},
+		{"APY raised with orders", change(func(p *models.StakingProduct) { p.DefaultAPY = 0.2 }), 1, 0, 0, false},
+		{"minimum redeem value raised with orders", change(func(p *models.StakingProduct) { p.MinRedeemValue = 10 }), 1, 0, 0, true},
+		{"minimum order value raised with a pending order", change(func(p *models.StakingProduct) { p.MinOrderValue = 20 }), 2, 1, 0, true},
+		{"minimum order value raised with only holding orders", change(func(p *models.StakingProduct) { p.MinOrderValue = 20 }), 2, 0, 100, false},
+		{"minimum order value lowered with a pending order", change(func(p *models.StakingProduct) { p.MinOrderValue = 5 }), 2, 1, 0, false},
+		{"top-up limit lowered below the staked principal", change(func(p *models.StakingProduct) { p.TopUpLimit = 400 }), 2, 0, 500, true},
+		{"top-up limit lowered to the staked principal", change(func(p *models.StakingProduct) { p.TopUpLimit = 500 }), 2, 0, 500, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProductUpdate(started, tt.updated, tt.liveOrders, tt.pendingOrders, tt.totalPrincipal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && apperrors.KindOf(err) != apperrors.KindConflict {
+				t.Errorf("error kind = %v, want a conflict", apperrors.KindOf(err))
+			}
+		})
+	}
+}
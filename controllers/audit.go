@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+// RequestIDContextKey holds the ID assigned to the current request
+const RequestIDContextKey = "requestID"
+
+const userActor = "user"
+
+// LogDIDKey and LogOrderIDKey hold the user DID and order ID a request concerns, for the access log
+const LogDIDKey = "logDID"
+const LogOrderIDKey = "logOrderID"
+
+func tagDID(c *gin.Context, did string) {
+	c.Set(LogDIDKey, did)
+}
+
+func tagOrder(c *gin.Context, orderID string) {
+	c.Set(LogOrderIDKey, orderID)
+}
+
+// actorFromContext identifies who is making the request for the audit log. Operator requests are
+// attributed to the operator; everything else comes from the public API.
+func actorFromContext(c *gin.Context) *models.Actor {
+	name := userActor
+	if operator := operatorName(c); operator != "" {
+		name = "operator:" + operator
+	}
+	return models.NewActor(name, c.GetString(RequestIDContextKey))
+}
+
+func GetAuditLogHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	opts, err := parseListOptions(c)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	filter := models.NewAuditFilter()
+	filter.EntityType = c.Query("entity_type")
+	filter.EntityID = c.Query("entity_id")
+	filter.Actor = c.Query("actor")
+
+	entries, nextKey, err := dao.GetAuditLog(ctx, filter, opts)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, models.NewPage(entries, encodeCursor(opts, nextKey)))
+}
+
+func VerifyAuditChainHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	verification, err := dao.VerifyAuditChain(ctx)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, verification)
+}
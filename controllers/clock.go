@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+// the clock endpoints are only registered when the fake clock is enabled; the offset is shared
+// through the database, so advancing it on one instance moves every instance within a sync interval
+
+func GetClockHandler(c *gin.Context) {
+	fake, ok := clock.Fake()
+	if !ok {
+		ResponseErr(c, apperrors.NotFound("fake clock is not enabled"))
+		return
+	}
+	ResponseSuccess(c, newClockOutput(fake))
+}
+
+func AdvanceClockHandler(c *gin.Context) {
+	fake, ok := clock.Fake()
+	if !ok {
+		ResponseErr(c, apperrors.NotFound("fake clock is not enabled"))
+		return
+	}
+
+	input := models.NewAdvanceClockInput()
+	if !bindJSON(c, input) {
+		return
+	}
+	duration, err := time.ParseDuration(input.Duration)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	if duration < 0 {
+		ResponseErr(c, apperrors.Validation("the clock can only be moved forward"))
+		return
+	}
+	err = dao.AdvanceFakeClock(c.Request.Context(), duration)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, newClockOutput(fake))
+}
+
+func newClockOutput(fake *clock.FakeClock) *models.ClockOutput {
+	output := models.NewClockOutput()
+	output.Now = models.NewTimestamp(fake.Now())
+	output.Offset = fake.Offset().String()
+	return output
+}
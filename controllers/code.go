@@ -7,6 +7,15 @@ const (
 	CodeInvalidAuth
 	CodeNeedLogin
 	CodeError
+	CodeIdempotencyKeyReused
+	CodeRequestInProgress
+	CodeInvalidParams
+	CodeNotFound
+	CodeConflict
+	CodeForbidden
+	CodeUpstreamError
+	CodeInternalError
+	CodeTooManyRequests
 )
 
 var codeMsgMap = map[ResCode]string{
@@ -14,6 +23,16 @@ var codeMsgMap = map[ResCode]string{
 	CodeInvalidAuth: "Invalid auth",
 	CodeNeedLogin:   "Please login first",
 	CodeError:       "error",
+
+	CodeIdempotencyKeyReused: "Idempotency-Key has already been used with a different request",
+	CodeRequestInProgress:    "a request with this Idempotency-Key is still being processed",
+	CodeInvalidParams:        "Invalid parameters",
+	CodeNotFound:             "not found",
+	CodeConflict:             "request conflicts with the current state",
+	CodeForbidden:            "request is not allowed",
+	CodeUpstreamError:        "blockchain request failed, please try again later",
+	CodeInternalError:        "internal server error",
+	CodeTooManyRequests:      "too many requests, please try again later",
 }
 
 func (c ResCode) Msg() string {
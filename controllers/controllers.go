@@ -1,102 +1,162 @@
 package controllers
 
 import (
-	"math"
+	"context"
+	"errors"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
 	"github.com/metabloxStaking/contract"
 	"github.com/metabloxStaking/dao"
 	"github.com/metabloxStaking/interest"
+	"github.com/metabloxStaking/logging"
 	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/payouts"
+	"github.com/metabloxStaking/products"
+	"github.com/metabloxStaking/settings"
+	"github.com/metabloxStaking/terms"
+	"github.com/metabloxStaking/treasury"
 )
 
 func GetProductInfoByIDHandler(c *gin.Context) {
-	productID := c.Param("id")
-	product, err := dao.GetProductInfoByID(productID)
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	productID := params.ID
+	product, err := dao.GetProductInfoByID(ctx, productID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 	product.CurrentAPY = 1234 //todo: get value from Colin's code
+	product.CurrentAPY = settings.Current().APY(product.ID, product.CurrentAPY)
 	ResponseSuccess(c, product)
 }
 
 func GetAllProductInfoHandler(c *gin.Context) {
-	products, err := dao.GetAllProductInfo()
+	ctx := c.Request.Context()
+	state := c.Query("state")
+	if state != "" && !products.IsValidState(state) {
+		ResponseErr(c, apperrors.Validation("state must be one of Upcoming, Open, SoldOut, Closed or Settled"))
+		return
+	}
+	productList, err := dao.GetAllProductInfo(ctx, state)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
-	ResponseSuccess(c, products)
+	runtime := settings.Current()
+	for _, product := range productList {
+		product.CurrentAPY = runtime.APY(product.ID, product.CurrentAPY)
+	}
+	ResponseSuccess(c, productList)
 }
 
 func CreateOrderHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	var err error
 	input := models.NewCreateOrderInput()
-	c.BindJSON(input)
+	if !bindJSON(c, input) {
+		return
+	}
+
+	product, err := dao.GetProductInfoByID(ctx, input.ProductID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	if product.State != models.ProductStateOpen {
+		ResponseErr(c, apperrors.Forbidden("product is not accepting new orders"))
+		return
+	}
+	if treasury.UnderCollateralized() {
+		ResponseErr(c, apperrors.Forbidden("new orders are paused while the treasury is under-collateralized"))
+		return
+	}
+	//SubmitBuyin checks these again under the product lock; rejecting here as well stops users
+	//sending a deposit for an order that could never be accepted
+	if input.Amount < float64(product.MinOrderValue) {
+		ResponseErr(c, dao.ErrBelowMinOrderValue)
+		return
+	}
+	totalPrincipal, err := dao.GetProductTotalPrincipal(ctx, product.ID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	if totalPrincipal+input.Amount > product.TopUpLimit {
+		ResponseErr(c, dao.ErrBuyinLimitExceeded)
+		return
+	}
 
 	newOrder := models.NewOrder()
 	newOrder.ProductID = input.ProductID
 
 	newOrder.UserDID = input.UserDID
+	tagDID(c, input.UserDID)
 	newOrder.Type = models.OrderTypePending
 	newOrder.PaymentAddress = "placeholder" //todo: find a way to lookup the correct value from the PaymentInfo table
 	newOrder.Term = new(int)
-	*newOrder.Term = 1
+	*newOrder.Term = product.Term
 	newOrder.Amount = input.Amount
 	newOrder.UserAddress = input.UserAddress
+	newOrder.AutoRenew = input.AutoRenew
+	newOrder.CompoundInterest = input.CompoundInterest
 
-	orderID, err := dao.CreateOrder(newOrder)
+	orderID, err := dao.CreateOrder(ctx, actorFromContext(c), newOrder)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
 	output := models.NewCreateOrderOutput()
 
 	output.OrderID = strconv.Itoa(orderID)
+	tagOrder(c, output.OrderID)
 	output.PaymentAddress = newOrder.PaymentAddress
 
 	ResponseSuccess(c, output)
 }
 
 func SubmitBuyinHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	input := models.NewSubmitBuyinInput()
-	c.BindJSON(input)
-
-	exists, err := dao.CheckIfTXExists(input.TxHash)
-	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
-		return
-	}
-	if exists {
-		ResponseErrorWithMsg(c, CodeError, "provided tx hash is already recorded in db")
+	if !bindJSON(c, input) {
 		return
 	}
 
-	completed, err := contract.CheckIfTransactionCompleted(input.TxHash)
+	exists, err := dao.CheckIfTXExists(ctx, input.TxHash)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
-	if !completed {
-		ResponseErrorWithMsg(c, CodeError, "transaction not yet completed")
+	if exists {
+		ResponseErr(c, apperrors.Conflict("provided tx hash is already recorded in db"))
 		return
 	}
 
 	txInfo := models.NewTXInfo()
 	txInfo.OrderID = input.OrderID
+	tagOrder(c, input.OrderID)
 
-	order, err := dao.GetOrderByID(input.OrderID)
+	order, err := dao.GetOrderByID(ctx, input.OrderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
-	product, err := dao.GetProductInfoByID(order.ProductID)
+	_, err = confirmDeposit(ctx, input.TxHash, order, order.Amount)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	product, err := dao.GetProductInfoByID(ctx, order.ProductID)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
@@ -107,16 +167,23 @@ func SubmitBuyinHandler(c *gin.Context) {
 	txInfo.Principal = order.Amount
 	txInfo.Interest = 0
 	txInfo.UserAddress = order.UserAddress
-	txInfo.RedeemableTime = time.Now().AddDate(0, 0, 179).Truncate(24 * time.Hour).Format("2006-01-02 15:04:05.000")
-	err = dao.SubmitBuyin(txInfo)
+	dates, err := terms.Compute(clock.Now(), product.Term, product.LockUpPeriod)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	dates.Apply(order, product.Term)
+	txInfo.RedeemableTime = order.MaturityDate
+	err = dao.SubmitBuyin(ctx, actorFromContext(c), txInfo, order)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
+	refreshProductState(c, order.ProductID)
 
-	date, err := dao.GetTXCreateDate(input.TxHash)
+	date, err := dao.GetTXCreateDate(ctx, input.TxHash)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
@@ -130,233 +197,579 @@ func SubmitBuyinHandler(c *gin.Context) {
 }
 
 func GetStakingRecordsHandler(c *gin.Context) {
-	userDID := c.Param("did")
-	records, err := dao.GetStakingRecords(userDID)
+	ctx := c.Request.Context()
+	params := models.NewDIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	userDID := params.DID
+	tagDID(c, userDID)
+	opts, err := parseListOptions(c)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	records, nextKey, err := dao.GetStakingRecords(ctx, userDID, opts)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
-	stmt, err := dao.PrepareGetInterestByOrderID()
+	stmt, err := dao.PrepareGetInterestByOrderID(ctx)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
 	for _, record := range records {
+		now := clock.Now()
+		record.IsInClosureWindow = !now.Before(record.RedeemableTime.Time) && now.Before(record.ClosureWindowEnd.Time)
 
-		purchaseTime, err := time.Parse("2006-01-02 15:04:05", record.PurchaseTime)
+		interestInfo, err := dao.ExecuteGetInterestStmt(ctx, record.OrderID, stmt)
 		if err != nil {
-			ResponseErrorWithMsg(c, CodeError, err.Error())
+			ResponseErr(c, err)
 			stmt.Close()
 			return
 		}
-		record.PurchaseTime = strconv.FormatFloat(float64(purchaseTime.UnixNano())/float64(time.Second), 'f', 3, 64)
-
-		redeemDate, err := time.Parse("2006-01-02 15:04:05", record.RedeemableTime)
-		if err != nil {
-			ResponseErrorWithMsg(c, CodeError, err.Error())
-			stmt.Close()
-			return
-		}
-		record.RedeemableTime = strconv.FormatFloat(float64(redeemDate.UnixNano())/float64(time.Second), 'f', 3, 64)
-
-		timeElapsed := time.Since(redeemDate)
-		record.IsInClosureWindow = (0 < timeElapsed.Hours() && timeElapsed.Hours() < 24)
+		record.InterestGain = interestInfo.AccumulatedInterest - interestInfo.TotalInterestGained
 
+		//whole days the accrual job has not recorded yet are shown without writing them here
 		if record.OrderStatus == models.OrderTypeHolding {
-			interest.CalculateInterest() //query Colin's code to update interest value in db
+			pending, err := interest.Preview(ctx, record.OrderID, now, false)
+			if err != nil {
+				ResponseErr(c, err)
+				stmt.Close()
+				return
+			}
+			if pending != nil {
+				record.InterestGain += pending.InterestGain
+			}
 		}
-
-		interestInfo, err := dao.ExecuteGetInterestStmt(record.OrderID, stmt)
-		if err != nil {
-			ResponseErrorWithMsg(c, CodeError, err.Error())
-			stmt.Close()
-			return
-		}
-		record.InterestGain = interestInfo.AccumulatedInterest - interestInfo.TotalInterestGained
 		record.TotalAmount = record.InterestGain + record.PrincipalAmount
 	}
 	stmt.Close()
-	ResponseSuccess(c, records)
+	ResponseSuccess(c, models.NewPage(records, encodeCursor(opts, nextKey)))
 }
 
 func GetTransactionsByOrderIDHandler(c *gin.Context) {
-	orderID := c.Param("id")
-	transactions, err := dao.GetTransactionsByOrderID(orderID)
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+	opts, err := parseListOptions(c)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	transactions, nextKey, err := dao.GetTransactionsByOrderID(ctx, orderID, opts)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
-	ResponseSuccess(c, transactions)
+	ResponseSuccess(c, models.NewPage(transactions, encodeCursor(opts, nextKey)))
 }
 
 func GetTransactionsByUserDIDHandler(c *gin.Context) {
-	userDID := c.Param("did")
-	transactions, err := dao.GetTransactionsByUserDID(userDID)
+	ctx := c.Request.Context()
+	params := models.NewDIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	userDID := params.DID
+	tagDID(c, userDID)
+	opts, err := parseListOptions(c)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	transactions, nextKey, err := dao.GetTransactionsByUserDID(ctx, userDID, opts)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
-	ResponseSuccess(c, transactions)
+	ResponseSuccess(c, models.NewPage(transactions, encodeCursor(opts, nextKey)))
 }
 
 func GetOrderInterestHandler(c *gin.Context) {
-	orderID := c.Param("id")
-	transactions, err := dao.GetOrderInterestByID(orderID)
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+	opts, err := parseListOptions(c)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	interests, nextKey, err := dao.GetOrderInterestByID(ctx, orderID, opts)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
-	ResponseSuccess(c, transactions)
+	ResponseSuccess(c, models.NewPage(interests, encodeCursor(opts, nextKey)))
 }
 
 func RedeemOrderHandler(c *gin.Context) {
-	orderID := c.Param("id")
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
 
-	redeemableDate, err := dao.GetOrderRedeemableDate(orderID)
+	redeemableDate, err := dao.GetOrderRedeemableDate(ctx, orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
-	redeemableTime, err := time.Parse("2006-01-02 15:04:05", redeemableDate)
+
+	order, err := dao.GetOrderByID(ctx, orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
-	elapsedTime := time.Since(redeemableTime)
-	elapsedDays := int(math.Floor(elapsedTime.Hours() / 24))
-	if elapsedDays != 0 {
-		ResponseErrorWithMsg(c, CodeError, "Order can only be redeemed on final day of term")
+	if order.Type != models.OrderTypeHolding && order.Type != models.OrderTypeMatured {
+		ResponseErr(c, apperrors.Conflict("only holding or matured orders can be redeemed"))
+		return
+	}
+
+	//matured orders missed their closure window and can be redeemed at any time
+	if order.Type != models.OrderTypeMatured {
+		now := clock.Now()
+		if now.Before(redeemableDate.Time) || !now.Before(order.ClosureWindowEnd.Time) {
+			ResponseErr(c, apperrors.Forbidden("Order can only be redeemed on final day of term"))
+			return
+		}
+	}
+
+	if treasury.UnderCollateralized() {
+		amount := order.Amount + order.AccumulatedInterest - order.TotalInterestGained
+		output, err := payouts.Queue(ctx, actorFromContext(c), orderID, models.PayoutTypeRedeem, amount)
+		if err != nil {
+			ResponseErr(c, err)
+			return
+		}
+		ResponseAccepted(c, output)
 		return
 	}
 
-	userAddress, err := dao.GetUserAddressByOrderID(orderID)
+	output, err := payouts.RedeemOrder(ctx, actorFromContext(c), orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
+	ResponseSuccess(c, output)
+}
 
-	interestInfo, err := dao.GetInterestInfoByOrderID(orderID)
+func RedeemInterestHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+
+	minInterest, err := dao.GetMinimumInterestByOrderID(ctx, orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
-	productName, err := dao.GetProductNameForOrder(orderID)
+	interestInfo, err := dao.GetInterestInfoByOrderID(ctx, orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
 
-	order, err := dao.GetOrderByID(orderID)
+	currentInterest := interestInfo.AccumulatedInterest - interestInfo.TotalInterestGained
+
+	if currentInterest < float64(minInterest) {
+		ResponseErr(c, apperrors.Forbidden("order does not meet minimum interest required to redeem"))
+		return
+	}
+
+	if treasury.UnderCollateralized() {
+		output, err := payouts.Queue(ctx, actorFromContext(c), orderID, models.PayoutTypeHarvest, currentInterest)
+		if err != nil {
+			ResponseErr(c, err)
+			return
+		}
+		ResponseAccepted(c, output)
+		return
+	}
+
+	output, err := payouts.RedeemInterest(ctx, actorFromContext(c), orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
+	ResponseSuccess(c, output)
+}
 
-	txHash := contract.RedeemOrder()
+// requireFeature refuses the request while the feature is switched off in the config. Confirming
+// an action is gated as well as starting it, so that switching a feature off takes effect at once.
+func requireFeature(c *gin.Context, feature string) bool {
+	if settings.Current().FeatureEnabled(feature) {
+		return true
+	}
+	ResponseErr(c, apperrors.Forbidden(feature+" is currently disabled"))
+	return false
+}
 
-	txInfo := models.NewTXInfo()
-	txInfo.OrderID = orderID
-	txInfo.TXCurrencyType = "MBLX"
-	txInfo.TXType = "Redeem"
-	txInfo.UserAddress = userAddress
-	txInfo.RedeemableTime = redeemableDate
-	txInfo.TXHash = new(string)
-	*txInfo.TXHash = txHash
+// refreshProductState moves the product to sold out as soon as a purchase fills it. The purchase
+// has already been recorded, so a failure is only logged and left to the scheduled job.
+func refreshProductState(c *gin.Context, productID string) {
+	ctx := c.Request.Context()
+	err := products.Refresh(ctx, productID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("failed to refresh state of product " + productID + ": " + err.Error())
+	}
+}
 
-	err = dao.UploadTransaction(txInfo)
+// confirmDeposit checks that the transaction moved exactly amount MBLX from the order's address into
+// the staking contract, and returns the amount as recorded on chain
+func confirmDeposit(ctx context.Context, txHash string, order *models.Order, amount float64) (float64, error) {
+	want, ok := contract.WeiFromTokens(amount)
+	if !ok {
+		return 0, apperrors.Validation("amount has more decimal places than the token")
+	}
+	deposit, err := contract.ConfirmedDeposit(ctx, txHash, order.UserAddress)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		return 0, err
+	}
+	if deposit == nil {
+		return 0, apperrors.Conflict("transaction not yet completed")
+	}
+	if deposit.Cmp(want) != 0 {
+		return 0, apperrors.Conflict("transaction did not transfer the amount from the order's address to the staking contract")
+	}
+	return contract.TokensFromWei(deposit), nil
+}
+
+func validateTopUp(ctx context.Context, order *models.Order, amount float64) error {
+	if order.Type != models.OrderTypeHolding {
+		return apperrors.Conflict("only holding orders can be topped up")
+	}
+
+	product, err := dao.GetProductInfoByID(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	if product.State != models.ProductStateOpen {
+		return apperrors.Forbidden("product is not accepting new orders")
+	}
+	if amount < float64(product.MinOrderValue) {
+		return apperrors.Validation("top-up amount is below the product's minimum order value")
+	}
+	maxOrderPrincipal := settings.Current().Staking.MaxOrderPrincipal
+	if maxOrderPrincipal > 0 && order.Amount+amount > maxOrderPrincipal {
+		return apperrors.Conflict("top-up would exceed the maximum principal allowed per order")
+	}
+	totalPrincipal, err := dao.GetProductTotalPrincipal(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	if totalPrincipal+amount > product.TopUpLimit {
+		return dao.ErrTopUpLimitExceeded
+	}
+
+	redeemableDate, err := dao.GetOrderRedeemableDate(ctx, order.OrderID)
+	if err != nil {
+		return err
+	}
+	if !clock.Now().Before(redeemableDate.Time) {
+		return apperrors.Forbidden("order has reached the end of its term and can no longer be topped up")
+	}
+	return nil
+}
+
+func TopUpOrderHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if !requireFeature(c, settings.FeatureTopUp) {
+		return
+	}
+	input := models.NewTopUpOrderInput()
+	if !bindJSON(c, input) {
 		return
 	}
 
-	output := models.NewRedeemOrderOutput()
-	output.Amount = (interestInfo.AccumulatedInterest - interestInfo.TotalInterestGained) + order.Amount
-	output.ProductName = productName
-	output.TXCurrencyType = "MBLX"
-	output.TXHash = txHash
-	output.Time = strconv.FormatFloat(float64(time.Now().UnixNano())/float64(time.Second), 'f', 3, 64)
-	output.ToAddress = userAddress
+	tagOrder(c, input.OrderID)
+	order, err := dao.GetOrderByID(ctx, input.OrderID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	err = validateTopUp(ctx, order, input.Amount)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
 
+	output := models.NewTopUpOrderOutput()
+	output.OrderID = order.OrderID
+	output.PaymentAddress = order.PaymentAddress
+	output.Amount = input.Amount
+	output.NewPrincipal = order.Amount + input.Amount
 	ResponseSuccess(c, output)
 }
 
-func RedeemInterestHandler(c *gin.Context) {
-	orderID := c.Param("id")
+func SubmitTopUpHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if !requireFeature(c, settings.FeatureTopUp) {
+		return
+	}
+	input := models.NewSubmitTopUpInput()
+	if !bindJSON(c, input) {
+		return
+	}
 
-	minInterest, err := dao.GetMinimumInterestByOrderID(orderID)
+	exists, err := dao.CheckIfTXExists(ctx, input.TxHash)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	if exists {
+		ResponseErr(c, apperrors.Conflict("provided tx hash is already recorded in db"))
 		return
 	}
 
-	interestInfo, err := dao.GetInterestInfoByOrderID(orderID)
+	tagOrder(c, input.OrderID)
+	order, err := dao.GetOrderByID(ctx, input.OrderID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	amount, err := confirmDeposit(ctx, input.TxHash, order, input.Amount)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	err = validateTopUp(ctx, order, amount)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
-	currentInterest := interestInfo.AccumulatedInterest - interestInfo.TotalInterestGained
+	productName, err := dao.GetProductNameForOrder(ctx, order.OrderID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	redeemableDate, err := dao.GetOrderRedeemableDate(ctx, order.OrderID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
 
-	if currentInterest < float64(minInterest) {
-		ResponseErrorWithMsg(c, CodeError, "order does not meet minimum interest required to redeem")
+	txInfo := models.NewTXInfo()
+	txInfo.OrderID = order.OrderID
+	txInfo.TXCurrencyType = "MBLX"
+	txInfo.TXType = "TopUp"
+	txInfo.TXHash = new(string)
+	*txInfo.TXHash = input.TxHash
+	txInfo.Principal = amount
+	txInfo.Interest = 0
+	txInfo.UserAddress = order.UserAddress
+	txInfo.RedeemableTime = redeemableDate
+	err = dao.SubmitTopUp(ctx, actorFromContext(c), txInfo, interest.Until(clock.Now(), true))
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
+	refreshProductState(c, order.ProductID)
 
-	txHash := contract.RedeemInterest()
+	date, err := dao.GetTXCreateDate(ctx, input.TxHash)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+
+	output := models.NewSubmitTopUpOutput()
+	output.ProductName = productName
+	output.Amount = amount
+	output.NewPrincipal = order.Amount + amount
+	output.Time = date
+	output.TXCurrencyType = txInfo.TXCurrencyType
+	output.UserAddress = txInfo.UserAddress
+	ResponseSuccess(c, output)
+}
+
+// quoteEarlyRedemption works out what an order would pay out if it were redeemed now
+func quoteEarlyRedemption(ctx context.Context, orderID string) (*models.EarlyRedemptionQuote, error) {
+	order, err := dao.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	redeemableDate, err := dao.GetOrderRedeemableDate(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	penaltyRate := settings.Current().Staking.EarlyRedemptionPenalty
+	if penaltyRate < 0 || penaltyRate > 1 {
+		return nil, apperrors.Internal(errors.New("staking.earlyRedemptionPenalty must be between 0 and 1"), "early redemption penalty is misconfigured")
+	}
+
+	now := clock.Now()
+	accrual, err := interest.Preview(ctx, orderID, now, true)
+	if err != nil {
+		return nil, err
+	}
+	var pending float64
+	if accrual != nil {
+		pending = accrual.InterestGain
+	}
+	return earlyRedemptionQuote(order, redeemableDate, pending, penaltyRate, now)
+}
+
+// earlyRedemptionQuote prices redeeming the order at now, given the interest earned since its last
+// accrual. Early redemption is only possible once the order's lock-up period has passed and
+// before it reaches the final day of its term.
+func earlyRedemptionQuote(order *models.Order, redeemableDate models.Timestamp, pending, penaltyRate float64, now time.Time) (*models.EarlyRedemptionQuote, error) {
+	err := checkEarlyRedemption(order, redeemableDate, now)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := models.NewEarlyRedemptionQuote()
+	quote.OrderID = order.OrderID
+	quote.Principal = order.Amount
+	quote.AccruedInterest = order.AccumulatedInterest - order.TotalInterestGained + pending
+	quote.PenaltyRate = penaltyRate
+	quote.ForfeitedInterest = quote.AccruedInterest * penaltyRate
+	quote.PayoutInterest = quote.AccruedInterest - quote.ForfeitedInterest
+	quote.TotalPayout = quote.Principal + quote.PayoutInterest
+	quote.LockUpEndTime = order.LockUpEndDate
+	quote.RedeemableTime = redeemableDate
+	return quote, nil
+}
+
+func checkEarlyRedemption(order *models.Order, redeemableDate models.Timestamp, now time.Time) error {
+	if order.Type != models.OrderTypeHolding {
+		return apperrors.Conflict("only holding orders can be redeemed early")
+	}
+	if now.Before(order.LockUpEndDate.Time) {
+		return apperrors.Forbidden("order is still within its lock-up period")
+	}
+	if !now.Before(redeemableDate.Time) {
+		return apperrors.Forbidden("order has reached the end of its term; use full redemption instead")
+	}
+	return nil
+}
+
+// validateLockedEarlyRedemption checks the term again on the locked order, which a concurrent
+// renewal may have moved since the quote
+func validateLockedEarlyRedemption(order *models.Order) error {
+	return checkEarlyRedemption(order, order.MaturityDate, clock.Now())
+}
 
-	err = dao.RedeemInterestByOrderID(orderID)
+func GetEarlyRedemptionQuoteHandler(c *gin.Context) {
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+	quote, err := quoteEarlyRedemption(c.Request.Context(), orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
+	ResponseSuccess(c, quote)
+}
 
-	userAddress, err := dao.GetUserAddressByOrderID(orderID)
+func EarlyRedeemOrderHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	if !requireFeature(c, settings.FeatureEarlyRedemption) {
+		return
+	}
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+	quote, err := quoteEarlyRedemption(ctx, orderID)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	//early redemption is optional for the user, so it is refused rather than queued
+	if treasury.UnderCollateralized() {
+		ResponseErr(c, apperrors.Forbidden("early redemption is unavailable while the treasury is under-collateralized"))
+		return
+	}
+
+	userAddress, err := dao.GetUserAddressByOrderID(ctx, orderID)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	productName, err := dao.GetProductNameForOrder(ctx, orderID)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
 	txInfo := models.NewTXInfo()
 	txInfo.OrderID = orderID
 	txInfo.TXCurrencyType = "MBLX"
-	txInfo.TXType = "Harvest"
+	txInfo.TXType = "EarlyRedeem"
 	txInfo.UserAddress = userAddress
-	txInfo.RedeemableTime = time.Now().Format("2006-01-02 15:04:05.000")
-	txInfo.TXHash = new(string)
-	*txInfo.TXHash = txHash
 
-	productName, err := dao.GetProductNameForOrder(orderID)
+	//the order is claimed before anything is paid out
+	forfeited, err := dao.SubmitEarlyRedemption(ctx, actorFromContext(c), txInfo, interest.Until(clock.Now(), true), validateLockedEarlyRedemption, quote.PenaltyRate)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
+		return
+	}
+	txHash := contract.RedeemOrder(ctx)
+	err = dao.SetTXHash(ctx, actorFromContext(c), txInfo.PaymentNo, txHash)
+	if err != nil {
+		ResponseErr(c, err)
 		return
 	}
 
-	output := models.NewRedeemOrderOutput()
-	output.Amount = currentInterest
+	output := models.NewEarlyRedeemOrderOutput()
+	output.Amount = txInfo.Principal + txInfo.Interest
+	output.ForfeitedInterest = forfeited
 	output.ProductName = productName
 	output.TXCurrencyType = "MBLX"
 	output.TXHash = txHash
-	output.Time = strconv.FormatFloat(float64(time.Now().UnixNano())/float64(time.Second), 'f', 3, 64)
+	output.Time = models.NewTimestamp(clock.Now())
 	output.ToAddress = userAddress
 
-	err = dao.UploadTransaction(txInfo)
-	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+	ResponseSuccess(c, output)
+}
+
+func SetRenewalPreferenceHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	params := models.NewIDParam()
+	if !bindURI(c, params) {
+		return
+	}
+	orderID := params.ID
+	tagOrder(c, orderID)
+	input := models.NewRenewalPreferenceInput()
+	if !bindJSON(c, input) {
 		return
 	}
 
-	err = dao.HarvestOrderInterest(orderID)
+	err := dao.SetOrderRenewal(ctx, actorFromContext(c), orderID, input.AutoRenew, input.CompoundInterest)
 	if err != nil {
-		ResponseErrorWithMsg(c, CodeError, err.Error())
+		ResponseErr(c, err)
 		return
 	}
-
-	ResponseSuccess(c, output)
+	ResponseSuccess(c, input)
 }
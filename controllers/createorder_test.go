@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+func TestCreateOrderLimits(t *testing.T) {
+	product := regexp.QuoteMeta("select ID, ProductName, MinOrderValue, TopUpLimit, LockUpPeriod, Term, State, MinRedeemValue from StakingProducts where ID = ?")
+	total := regexp.QuoteMeta("select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'")
+	productColumns := []string{"ID", "ProductName", "MinOrderValue", "TopUpLimit", "LockUpPeriod", "Term", "State", "MinRedeemValue"}
+
+	tests := []struct {
+		name       string
+		amount     string
+		staked     float64
+		wantStatus int
+	}{
+		{"below the minimum order value", "50", 0, http.StatusBadRequest},
+		{"over the remaining capacity", "200", 900, http.StatusConflict},
+		//the order is accepted and fails only when it is stored
+		{"exactly fills the capacity", "100", 900, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			mock.ExpectQuery(product).WithArgs("1").WillReturnRows(sqlmock.NewRows(productColumns).AddRow("1", "MBLX 30", 100, 1000, 7, 30, models.ProductStateOpen, 0))
+			if tt.wantStatus != http.StatusBadRequest {
+				mock.ExpectQuery(total).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.staked))
+			}
+			if tt.wantStatus == http.StatusInternalServerError {
+				mock.ExpectBegin().WillReturnError(errors.New("connection refused"))
+			}
+
+			body := `{"Amount": ` + tt.amount + `, "UserAddress": "0x52908400098527886E0F7030069857D2E4169EE7", "UserDID": "did:metablox:alice", "ProductID": "1"}`
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/staking/orders", bytes.NewBufferString(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			CreateOrderHandler(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
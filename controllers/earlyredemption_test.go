@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+)
+
+func TestEarlyRedemptionQuote(t *testing.T) {
+	lockUpEnd := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	redeemable := models.NewTimestamp(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC))
+	holding := func() *models.Order {
+		return &models.Order{
+			OrderID:             "7",
+			Type:                models.OrderTypeHolding,
+			Amount:              1000,
+			AccumulatedInterest: 30,
+			TotalInterestGained: 10,
+			LockUpEndDate:       models.NewTimestamp(lockUpEnd),
+		}
+	}
+	matured := holding()
+	matured.Type = models.OrderTypeMatured
+
+	tests := []struct {
+		name          string
+		order         *models.Order
+		pending       float64
+		penaltyRate   float64
+		now           time.Time
+		wantKind      apperrors.Kind
+		wantErr       bool
+		wantAccrued   float64
+		wantForfeited float64
+		wantTotal     float64
+	}{
+		{
+			name:          "after the lock-up",
+			order:         holding(),
+			pending:       5,
+			penaltyRate:   0.5,
+			now:           lockUpEnd.Add(time.Hour),
+			wantAccrued:   25,
+			wantForfeited: 12.5,
+			wantTotal:     1012.5,
+		},
+		{
+			name:          "on the day the lock-up ends",
+			order:         holding(),
+			penaltyRate:   0.25,
+			now:           lockUpEnd,
+			wantAccrued:   20,
+			wantForfeited: 5,
+			wantTotal:     1015,
+		},
+		{
+			name:        "no penalty",
+			order:       holding(),
+			pending:     1,
+			penaltyRate: 0,
+			now:         lockUpEnd.Add(time.Hour),
+			wantAccrued: 21,
+			wantTotal:   1021,
+		},
+		{
+			name:          "all interest forfeited",
+			order:         holding(),
+			penaltyRate:   1,
+			now:           lockUpEnd.Add(time.Hour),
+			wantAccrued:   20,
+			wantForfeited: 20,
+			wantTotal:     1000,
+		},
+		{
+			name:     "within the lock-up",
+			order:    holding(),
+			now:      lockUpEnd.Add(-time.Second),
+			wantErr:  true,
+			wantKind: apperrors.KindForbidden,
+		},
+		{
+			name:     "at the end of the term",
+			order:    holding(),
+			now:      redeemable.Time,
+			wantErr:  true,
+			wantKind: apperrors.KindForbidden,
+		},
+		{
+			name:     "not holding",
+			order:    matured,
+			now:      lockUpEnd.Add(time.Hour),
+			wantErr:  true,
+			wantKind: apperrors.KindConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := earlyRedemptionQuote(tt.order, redeemable, tt.pending, tt.penaltyRate, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if apperrors.KindOf(err) != tt.wantKind {
+					t.Errorf("error kind = %v, want %v", apperrors.KindOf(err), tt.wantKind)
+				}
+				return
+			}
+			if math.Abs(quote.AccruedInterest-tt.wantAccrued) > 1e-9 {
+				t.Errorf("AccruedInterest = %v, want %v", quote.AccruedInterest, tt.wantAccrued)
+			}
+			if math.Abs(quote.ForfeitedInterest-tt.wantForfeited) > 1e-9 {
+				t.Errorf("ForfeitedInterest = %v, want %v", quote.ForfeitedInterest, tt.wantForfeited)
+			}
+			if math.Abs(quote.TotalPayout-tt.wantTotal) > 1e-9 {
+				t.Errorf("TotalPayout = %v, want %v", quote.TotalPayout, tt.wantTotal)
+			}
+			if quote.Principal+quote.PayoutInterest != quote.TotalPayout {
+				t.Errorf("Principal + PayoutInterest = %v, want TotalPayout %v", quote.Principal+quote.PayoutInterest, quote.TotalPayout)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/jobs"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+const defaultDBPingTimeout = 2 * time.Second
+const defaultMaxBlockAge = 2 * time.Minute
+
+// Health endpoints answer with the report itself rather than the usual response envelope, since
+// orchestrators only look at the status code.
+
+// HealthzHandler reports that the process is alive and serving requests
+func HealthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewHealthReport())
+}
+
+// ReadyzHandler reports whether the service's dependencies are working. Any failing check or
+// stalled job makes the service unready.
+func ReadyzHandler(c *gin.Context) {
+	report := models.NewHealthReport()
+	report.Checks["mysql"] = checkDatabase(c.Request.Context())
+	report.Checks["chain"] = checkChain(c.Request.Context())
+	report.Jobs = jobs.Heartbeats()
+
+	healthy := true
+	for _, check := range report.Checks {
+		if check.Status == models.HealthStatusFailing {
+			healthy = false
+		}
+	}
+	for _, job := range report.Jobs {
+		if !job.Healthy {
+			healthy = false
+		}
+	}
+
+	status := http.StatusOK
+	if !healthy {
+		report.Status = models.HealthStatusFailing
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+func checkDatabase(ctx context.Context) *models.HealthCheck {
+	timeout := settings.Current().Health.DBPingTimeout
+	if timeout <= 0 {
+		timeout = defaultDBPingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := dao.Ping(ctx)
+	if err != nil {
+		return models.NewHealthCheck(models.HealthStatusFailing, err.Error())
+	}
+	return models.NewHealthCheck(models.HealthStatusOK, "")
+}
+
+func checkChain(ctx context.Context) *models.HealthCheck {
+	//deposits and payouts cannot be confirmed without the chain, so a missing client is a failure
+	if !contract.Connected() {
+		return models.NewHealthCheck(models.HealthStatusFailing, "chain client is not connected")
+	}
+	maxAge := settings.Current().Health.MaxBlockAge
+	if maxAge <= 0 {
+		maxAge = defaultMaxBlockAge
+	}
+
+	age, err := contract.LatestBlockAge(ctx)
+	if err != nil {
+		return models.NewHealthCheck(models.HealthStatusFailing, err.Error())
+	}
+	detail := "latest block is " + age.Round(time.Second).String() + " old"
+	if age > maxAge {
+		return models.NewHealthCheck(models.HealthStatusFailing, detail)
+	}
+	return models.NewHealthCheck(models.HealthStatusOK, detail)
+}
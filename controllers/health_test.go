@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+func TestReadyzHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		pingErr    error
+		wantMySQL  string
+		wantChain  string
+		wantStatus int
+	}{
+		{"database up, chain not connected", nil, models.HealthStatusOK, models.HealthStatusFailing, http.StatusServiceUnavailable},
+		{"database down, chain not connected", errors.New("connection refused"), models.HealthStatusFailing, models.HealthStatusFailing, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			mock.ExpectPing().WillReturnError(tt.pingErr)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			ReadyzHandler(c)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			report := models.NewHealthReport()
+			err = json.Unmarshal(w.Body.Bytes(), report)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Checks["mysql"].Status != tt.wantMySQL {
+				t.Errorf("mysql check = %s, want %s", report.Checks["mysql"].Status, tt.wantMySQL)
+			}
+			if report.Checks["chain"].Status != tt.wantChain {
+				t.Errorf("chain check = %s, want %s", report.Checks["chain"].Status, tt.wantChain)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/dao"
+)
+
+func GetLedgerBalancesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	balances, err := dao.GetLedgerBalances(ctx)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, balances)
+}
+
+func CheckLedgerHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	check, err := dao.CheckLedger(ctx)
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, check)
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+const defaultPageLimit = 20
+const defaultMaxPageLimit = 100
+
+const cursorSeparator = ":"
+
+// cursorScopeLength is how many bytes of the scope hash a cursor carries
+const cursorScopeLength = 8
+
+// parseListOptions reads the limit, cursor, sort and filter query parameters shared by the list endpoints
+func parseListOptions(c *gin.Context) (*models.ListOptions, error) {
+	opts := models.NewListOptions()
+
+	pagination := settings.Current().Pagination
+	maxLimit := pagination.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = defaultMaxPageLimit
+	}
+	opts.Limit = pagination.DefaultLimit
+	if opts.Limit <= 0 {
+		opts.Limit = defaultPageLimit
+	}
+	if limit := c.Query("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return nil, apperrors.Validation("limit must be a positive integer")
+		}
+		opts.Limit = value
+	}
+	if opts.Limit > maxLimit {
+		opts.Limit = maxLimit
+	}
+
+	opts.Sort = c.DefaultQuery("sort", models.SortDescending)
+	if opts.Sort != models.SortAscending && opts.Sort != models.SortDescending {
+		return nil, apperrors.Validation("sort must be either asc or desc")
+	}
+
+	opts.Scope = listScope(c.Request.URL.Path, c.Request.URL.Query(), opts.Sort)
+	cursor, err := decodeCursor(c.Query("cursor"), opts.Scope)
+	if err != nil {
+		return nil, err
+	}
+	opts.Cursor = cursor
+
+	opts.Status = c.Query("status")
+	opts.TXType = c.Query("tx_type")
+	opts.ProductID = c.Query("product")
+
+	opts.From, err = parseDateFilter(c.Query("from"), false)
+	if err != nil {
+		return nil, apperrors.Validation("from must be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	opts.To, err = parseDateFilter(c.Query("to"), true)
+	if err != nil {
+		return nil, apperrors.Validation("to must be a date (2006-01-02) or an RFC 3339 timestamp")
+	}
+	return opts, nil
+}
+
+// parseDateFilter accepts either a plain date or a full timestamp. A plain date used as the end of
+// a range covers the whole of that day.
+func parseDateFilter(value string, endOfRange bool) (models.Timestamp, error) {
+	if value == "" {
+		return models.Timestamp{}, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err == nil {
+		if endOfRange {
+			date = date.AddDate(0, 0, 1)
+		}
+		return models.NewTimestamp(date), nil
+	}
+	date, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return models.Timestamp{}, err
+	}
+	return models.NewTimestamp(date), nil
+}
+
+// listScope identifies a listing by its path, which holds the DID or order ID, its filters and its
+// sort order. The page size and cursor are left out since they change from page to page, as is
+// the choice of timestamp format.
+func listScope(path string, query url.Values, sortOrder string) string {
+	hash := sha256.New()
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(sortOrder))
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != "cursor" && key != "limit" && key != "sort" && key != EpochMillisQuery {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range query[key] {
+			hash.Write([]byte{0})
+			hash.Write([]byte(key + "=" + value))
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)[:cursorScopeLength])
+}
+
+// cursors are opaque to clients; they wrap the key of the last row on the previous page and the
+// scope of the listing it came from
+func encodeCursor(opts *models.ListOptions, key string) string {
+	if key == "" {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(key + cursorSeparator + opts.Scope))
+}
+
+func decodeCursor(cursor, scope string) (string, error) {
+	if cursor == "" {
+		return "", nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", apperrors.Validation("invalid cursor")
+	}
+	parts := strings.SplitN(string(data), cursorSeparator, 2)
+	if len(parts) != 2 {
+		return "", apperrors.Validation("invalid cursor")
+	}
+	if _, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return "", apperrors.Validation("invalid cursor")
+	}
+	if parts[1] != scope {
+		return "", apperrors.Validation("cursor belongs to a different listing; start again without a cursor after changing the filters or sort order")
+	}
+	return parts[0], nil
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/apperrors"
+)
+
+func listOptionsFor(t *testing.T, target string) (*gin.Context, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	_, err := parseListOptions(c)
+	return c, err
+}
+
+func TestCursorScope(t *testing.T) {
+	const origin = "/staking/transactions/user/did:metablox:alice?status=Holding&sort=asc&limit=5"
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, origin, nil)
+	opts, err := parseListOptions(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := encodeCursor(opts, "42")
+
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{"same listing", "/staking/transactions/user/did:metablox:alice?status=Holding&sort=asc&cursor=" + cursor, false},
+		{"same listing with a different page size", "/staking/transactions/user/did:metablox:alice?limit=50&status=Holding&sort=asc&cursor=" + cursor, false},
+		{"different user", "/staking/transactions/user/did:metablox:bob?status=Holding&sort=asc&cursor=" + cursor, true},
+		{"different endpoint", "/staking/orders/did:metablox:alice?status=Holding&sort=asc&cursor=" + cursor, true},
+		{"different filter", "/staking/transactions/user/did:metablox:alice?status=Complete&sort=asc&cursor=" + cursor, true},
+		{"filter removed", "/staking/transactions/user/did:metablox:alice?sort=asc&cursor=" + cursor, true},
+		{"filter added", "/staking/transactions/user/did:metablox:alice?status=Holding&product=3&sort=asc&cursor=" + cursor, true},
+		{"different sort order", "/staking/transactions/user/did:metablox:alice?status=Holding&sort=desc&cursor=" + cursor, true},
+		{"cursor without a scope", "/staking/transactions/user/did:metablox:alice?status=Holding&sort=asc&cursor=" + base64.RawURLEncoding.EncodeToString([]byte("42")), true},
+		{"cursor with a non-numeric key", "/staking/transactions/user/did:metablox:alice?status=Holding&sort=asc&cursor=" + base64.RawURLEncoding.EncodeToString([]byte("x:"+opts.Scope)), true},
+		{"cursor that is not base64", "/staking/transactions/user/did:metablox:alice?status=Holding&sort=asc&cursor=!!!", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := listOptionsFor(t, tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && apperrors.KindOf(err) != apperrors.KindValidation {
+				t.Errorf("error kind = %v, want validation", apperrors.KindOf(err))
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	c, err := listOptionsFor(t, "/staking/interest/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, err := parseListOptions(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := encodeCursor(opts, ""); got != "" {
+		t.Errorf("encodeCursor of the last page = %q, want empty", got)
+	}
+	key, err := decodeCursor(encodeCursor(opts, "1234"), opts.Scope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "1234" {
+		t.Errorf("key = %q, want 1234", key)
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/logging"
+	"github.com/metabloxStaking/models"
 )
 
 type ResponseData struct {
@@ -12,6 +17,17 @@ type ResponseData struct {
 	Data interface{} `json:"data"`
 }
 
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+type ValidationResponseData struct {
+	Code   ResCode      `json:"code"`
+	Msg    interface{}  `json:"msg"`
+	Errors []FieldError `json:"errors"`
+}
+
 func ResponseError(c *gin.Context, code ResCode) {
 	c.JSON(http.StatusBadRequest, &ResponseData{
 		code,
@@ -28,11 +44,82 @@ func ResponseErrorWithMsg(c *gin.Context, code ResCode, msg interface{}) {
 	})
 }
 
+func ResponseErrorWithStatus(c *gin.Context, status int, code ResCode) {
+	c.JSON(status, &ResponseData{
+		code,
+		code.Msg(),
+		nil,
+	})
+}
+
+var errorKindResponses = map[apperrors.Kind]struct {
+	status int
+	code   ResCode
+}{
+	apperrors.KindNotFound:   {http.StatusNotFound, CodeNotFound},
+	apperrors.KindConflict:   {http.StatusConflict, CodeConflict},
+	apperrors.KindForbidden:  {http.StatusForbidden, CodeForbidden},
+	apperrors.KindValidation: {http.StatusBadRequest, CodeInvalidParams},
+	apperrors.KindUpstream:   {http.StatusBadGateway, CodeUpstreamError},
+	apperrors.KindInternal:   {http.StatusInternalServerError, CodeInternalError},
+}
+
+// ResponseErr reports err with the HTTP status and code for its kind. Internal and upstream
+// details are only logged; the client gets the generic message for the code.
+func ResponseErr(c *gin.Context, err error) {
+	kind := apperrors.KindOf(err)
+	response := errorKindResponses[kind]
+
+	var msg interface{} = apperrors.Message(err)
+	if kind == apperrors.KindInternal || kind == apperrors.KindUpstream {
+		logging.FromContext(c.Request.Context()).WithFields(logger.Fields{
+			"method": c.Request.Method,
+			"path":   c.Request.URL.Path,
+			"kind":   kind.String(),
+		}).Error(err.Error())
+		msg = response.code.Msg()
+	}
+
+	c.JSON(response.status, &ResponseData{
+		response.code,
+		msg,
+		nil,
+	})
+}
+
+func ResponseValidationError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, &ValidationResponseData{
+		CodeInvalidParams,
+		CodeInvalidParams.Msg(),
+		fieldErrors(err),
+	})
+}
+
+// EpochMillisQuery is the query parameter that asks for timestamps as epoch milliseconds as well
+const EpochMillisQuery = "epoch_millis"
+
+// responseData adds epoch millisecond timestamps to data when the request asks for them
+func responseData(c *gin.Context, data interface{}) interface{} {
+	if c.Query(EpochMillisQuery) != "true" {
+		return data
+	}
+	return models.WithEpochMillis(data)
+}
+
 func ResponseSuccess(c *gin.Context, data interface{}) {
 	c.JSON(http.StatusOK, &ResponseData{
 		CodeSuccess,
 		CodeSuccess.Msg(),
-		data,
+		responseData(c, data),
+	})
+}
+
+// ResponseAccepted reports that the request was recorded but will be completed later
+func ResponseAccepted(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusAccepted, &ResponseData{
+		CodeSuccess,
+		CodeSuccess.Msg(),
+		responseData(c, data),
 	})
 }
 
@@ -52,6 +139,6 @@ func ResponseSuccessWithMsgAndData(c *gin.Context, msg interface{}, data interfa
 	c.JSON(http.StatusOK, &ResponseData{
 		CodeSuccess,
 		msg,
-		data,
+		responseData(c, data),
 	})
 }
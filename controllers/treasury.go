@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/treasury"
+)
+
+func GetTreasuryStatusHandler(c *gin.Context) {
+	ResponseSuccess(c, treasury.Status())
+}
+
+func GetPayoutsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	payouts, err := dao.GetPayouts(ctx, c.Query("status"))
+	if err != nil {
+		ResponseErr(c, err)
+		return
+	}
+	ResponseSuccess(c, payouts)
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var didPattern = regexp.MustCompile(`^did:[a-z0-9]+:[A-Za-z0-9._%-]+(:[A-Za-z0-9._%-]+)*$`)
+var txHashPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+var numericIDPattern = regexp.MustCompile(`^[1-9][0-9]{0,19}$`)
+
+var validationReasons = map[string]string{
+	"required":   "is required",
+	"gt":         "must be greater than zero",
+	"eth_addr":   "must be a checksummed hex address",
+	"did":        "must be a valid DID",
+	"tx_hash":    "must be a 32-byte hex transaction hash",
+	"numeric_id": "must be a numeric ID",
+	"duration":   "must be a duration such as 24h",
+}
+
+// RegisterValidators adds the custom binding tags used by the request models
+func RegisterValidators() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected binding validator engine")
+	}
+	validators := map[string]validator.Func{
+		"eth_addr":   validateEthAddress,
+		"did":        matchPattern(didPattern),
+		"tx_hash":    matchPattern(txHashPattern),
+		"numeric_id": matchPattern(numericIDPattern),
+		"duration":   validateDuration,
+	}
+	for tag, fn := range validators {
+		err := engine.RegisterValidation(tag, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEthAddress only accepts EIP-55 checksummed addresses, so that a mistyped address is
+// rejected instead of receiving a payout
+func validateEthAddress(fl validator.FieldLevel) bool {
+	address := fl.Field().String()
+	return common.IsHexAddress(address) && common.HexToAddress(address).Hex() == address
+}
+
+func validateDuration(fl validator.FieldLevel) bool {
+	_, err := time.ParseDuration(fl.Field().String())
+	return err == nil
+}
+
+func matchPattern(pattern *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return pattern.MatchString(fl.Field().String())
+	}
+}
+
+// bindJSON binds and validates the request body, responding with the invalid fields on failure
+func bindJSON(c *gin.Context, input interface{}) bool {
+	err := c.ShouldBindJSON(input)
+	if err != nil {
+		ResponseValidationError(c, err)
+		return false
+	}
+	return true
+}
+
+// bindURI binds and validates the path parameters, responding with the invalid fields on failure
+func bindURI(c *gin.Context, params interface{}) bool {
+	err := c.ShouldBindUri(params)
+	if err != nil {
+		ResponseValidationError(c, err)
+		return false
+	}
+	return true
+}
+
+func fieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fieldErr := range validationErrors {
+			reason, ok := validationReasons[fieldErr.Tag()]
+			if !ok {
+				reason = "failed " + fieldErr.Tag() + " validation"
+			}
+			fields = append(fields, FieldError{fieldErr.Field(), reason})
+		}
+		return fields
+	}
+
+	if errors.Is(err, io.EOF) {
+		return []FieldError{{"body", "is required"}}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []FieldError{{typeErr.Field, "must be of type " + typeErr.Type.String()}}
+	}
+	return []FieldError{{"body", err.Error()}}
+}
@@ -0,0 +1,247 @@
+package dao
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+// Every change to an order, transaction or interest row is recorded in AuditLog inside the same
+// database transaction as the change itself. Rows are only ever inserted, and triggers reject
+// updates and deletes. With audit.hashChain enabled each row also stores the SHA-256 of its
+// content chained to the previous row's hash, kept in AuditChainHead, so editing or deleting a row
+// breaks every hash after it.
+
+func insertAudit(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, entityType, entityID, action string, old, new interface{}) error {
+	entry := models.NewAuditEntry()
+	entry.EntityType = entityType
+	entry.EntityID = entityID
+	entry.Action = action
+	entry.Actor = actor.Name
+	entry.RequestID = actor.RequestID
+	//DATETIME columns keep whole seconds, so hash the value that will be read back
+	entry.CreateDate = models.NewTimestamp(clock.Now().Truncate(time.Second))
+
+	var err error
+	entry.OldValue, err = marshalAuditValue(old)
+	if err != nil {
+		return err
+	}
+	entry.NewValue, err = marshalAuditValue(new)
+	if err != nil {
+		return err
+	}
+
+	chained := settings.Current().AuditHashChain
+	if chained {
+		//locking the chain head serializes writers so that the chain cannot fork
+		var prevHash sql.NullString
+		sqlStr := "select Hash from AuditChainHead where ID = 1 for update"
+		err = dbTX.GetContext(ctx, &prevHash, sqlStr)
+		if err != nil {
+			return err
+		}
+		if prevHash.Valid {
+			entry.PrevHash = &prevHash.String
+		}
+		hash := auditHash(entry)
+		entry.Hash = &hash
+	}
+
+	sqlStr := "insert into AuditLog (EntityType, EntityID, Action, OldValue, NewValue, Actor, RequestID, PrevHash, Hash, CreateDate) values (:EntityType, :EntityID, :Action, :OldValue, :NewValue, :Actor, :RequestID, :PrevHash, :Hash, :CreateDate)"
+	_, err = dbTX.NamedExecContext(ctx, sqlStr, entry)
+	if err != nil || !chained {
+		return err
+	}
+	sqlStr = "update AuditChainHead set Hash = ? where ID = 1"
+	_, err = dbTX.ExecContext(ctx, sqlStr, entry.Hash)
+	return err
+}
+
+func auditHash(entry *models.AuditEntry) string {
+	hash := sha256.New()
+	write := func(value string) {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	write(stringOrEmpty(entry.PrevHash))
+	write(entry.EntityType)
+	write(entry.EntityID)
+	write(entry.Action)
+	write(stringOrEmpty(entry.OldValue))
+	write(stringOrEmpty(entry.NewValue))
+	write(entry.Actor)
+	write(entry.RequestID)
+	write(entry.CreateDate.UTC().Format(time.RFC3339))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+func marshalAuditValue(value interface{}) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+	return &text, nil
+}
+
+// lockOrder reads an order and holds its row lock until the transaction ends, so the audit
+// entry sees exactly the state that the change replaced
+func lockOrder(ctx context.Context, dbTX *sqlx.Tx, orderID string) (*models.Order, error) {
+	order := models.NewOrder()
+	sqlStr := "select * from Orders where OrderID = ? for update"
+	err := dbTX.GetContext(ctx, order, sqlStr, orderID)
+	if err != nil {
+		return nil, notFound(err, "order")
+	}
+	return order, nil
+}
+
+// auditOrder records the change from old to the order's current state within the transaction
+func auditOrder(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, action string, old *models.Order) error {
+	current := models.NewOrder()
+	sqlStr := "select * from Orders where OrderID = ?"
+	err := dbTX.GetContext(ctx, current, sqlStr, old.OrderID)
+	if err != nil {
+		return err
+	}
+	return insertAudit(ctx, dbTX, actor, models.AuditEntityOrder, old.OrderID, action, old, current)
+}
+
+// insertTX stores a new transaction and records it in the audit log
+func insertTX(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, tx *models.TXInfo) error {
+	stampTX(tx)
+	result, err := dbTX.NamedExecContext(ctx, insertTXInfoStr, tx)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	tx.PaymentNo = strconv.FormatInt(id, 10)
+	return insertAudit(ctx, dbTX, actor, models.AuditEntityTransaction, tx.PaymentNo, tx.TXType, nil, tx)
+}
+
+// zeroLatestInterest marks the order's unharvested interest as paid out
+func zeroLatestInterest(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, orderID, action string) error {
+	latest := models.NewOrderInterest()
+	sqlStr := "select * from OrderInterest where OrderID = ? order by ID desc limit 1 for update"
+	err := dbTX.GetContext(ctx, latest, sqlStr, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	sqlStr = "update OrderInterest set TotalInterestGain = 0 where ID = ?"
+	_, err = dbTX.ExecContext(ctx, sqlStr, latest.ID)
+	if err != nil {
+		return err
+	}
+	updated := *latest
+	updated.TotalInterestGain = 0
+	return insertAudit(ctx, dbTX, actor, models.AuditEntityOrderInterest, latest.ID, action, latest, &updated)
+}
+
+func GetAuditLog(ctx context.Context, filter *models.AuditFilter, opts *models.ListOptions) ([]*models.AuditEntry, string, error) {
+	ctx, done := queryContext(ctx, "GetAuditLog")
+	defer done()
+	var entries []*models.AuditEntry
+	query := newListQuery("ID > 0")
+	if filter.EntityType != "" {
+		query.where("EntityType = ?", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		query.where("EntityID = ?", filter.EntityID)
+	}
+	if filter.Actor != "" {
+		query.where("Actor = ?", filter.Actor)
+	}
+	query.whereDateRange("CreateDate", opts)
+	sqlStr, args := query.build("select ID, EntityType, EntityID, Action, OldValue, NewValue, Actor, RequestID, PrevHash, Hash, CreateDate from AuditLog", "ID", opts)
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		entry := models.NewAuditEntry()
+		err = rows.StructScan(entry)
+		if err != nil {
+			return nil, "", err
+		}
+		entries = append(entries, entry)
+	}
+	next := nextKey(len(entries), opts, func(i int) string { return entries[i].ID })
+	if next != "" {
+		entries = entries[:opts.Limit]
+	}
+	return entries, next, rows.Err()
+}
+
+// auditVerifyBatchSize is how many rows VerifyAuditChain reads per statement, so that each
+// statement finishes well within mysql.queryTimeout however long the log grows
+var auditVerifyBatchSize = 1000
+
+// VerifyAuditChain recomputes the hash of every chained audit row in order and reports the first
+// row whose stored hash or link to the previous row does not match. Rows are read in batches by ID,
+// each under its own query timeout.
+func VerifyAuditChain(ctx context.Context) (*models.AuditVerification, error) {
+	verification := models.NewAuditVerification()
+	afterID, prevHash := "0", ""
+	for {
+		entries, err := getChainedAuditBatch(ctx, afterID)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			verification.CheckedCount++
+			if stringOrEmpty(entry.PrevHash) != prevHash || auditHash(entry) != *entry.Hash {
+				verification.FirstBrokenID = entry.ID
+				return verification, nil
+			}
+			prevHash = *entry.Hash
+		}
+		if len(entries) < auditVerifyBatchSize {
+			verification.Valid = true
+			return verification, nil
+		}
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+func getChainedAuditBatch(ctx context.Context, afterID string) ([]*models.AuditEntry, error) {
+	ctx, done := queryContext(ctx, "VerifyAuditChain")
+	defer done()
+	var entries []*models.AuditEntry
+	sqlStr := "select ID, EntityType, EntityID, Action, OldValue, NewValue, Actor, RequestID, PrevHash, Hash, CreateDate from AuditLog where Hash is not null and ID > ? order by ID limit ?"
+	err := SqlDB.SelectContext(ctx, &entries, sqlStr, afterID, auditVerifyBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
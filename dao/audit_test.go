@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+func TestInsertAuditChain(t *testing.T) {
+	head := regexp.QuoteMeta("select Hash from AuditChainHead where ID = 1 for update")
+	insert := regexp.QuoteMeta("insert into AuditLog")
+	advance := regexp.QuoteMeta("update AuditChainHead set Hash = ? where ID = 1")
+	prev := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name     string
+		hashed   bool
+		headHash interface{}
+		wantPrev string
+	}{
+		{name: "first row in the chain", hashed: true, headHash: nil},
+		{name: "row linked to the chain head", hashed: true, headHash: prev, wantPrev: prev},
+		{name: "chain disabled", hashed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings.Set(&settings.Runtime{AuditHashChain: tt.hashed})
+			defer settings.Set(&settings.Runtime{})
+			mock := mockDB(t)
+			mock.ExpectBegin()
+			if tt.hashed {
+				mock.ExpectQuery(head).WillReturnRows(sqlmock.NewRows([]string{"Hash"}).AddRow(tt.headHash))
+			}
+			mock.ExpectExec(insert).WillReturnResult(sqlmock.NewResult(1, 1))
+			if tt.hashed {
+				//the new head is the hash of the row just inserted, which links to the old head
+				mock.ExpectExec(advance).WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			dbTX, err := SqlDB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = insertAudit(context.Background(), dbTX, models.NewActor("test", "req"), models.AuditEntityOrder, "7", "Create", nil, models.NewOrder())
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dbTX.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestAuditHashLinks(t *testing.T) {
+	first := models.NewAuditEntry()
+	first.EntityType = models.AuditEntityOrder
+	first.EntityID = "7"
+	first.Action = "Create"
+	firstHash := auditHash(first)
+
+	second := *first
+	second.PrevHash = &firstHash
+	other := "ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+	forked := *first
+	forked.PrevHash = &other
+
+	tests := []struct {
+		name  string
+		a, b  *models.AuditEntry
+		equal bool
+	}{
+		{"same content", first, first, true},
+		{"linked to the previous row", first, &second, false},
+		{"linked to a different previous row", &second, &forked, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditHash(tt.a) == auditHash(tt.b); got != tt.equal {
+				t.Errorf("hashes equal = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	batch := regexp.QuoteMeta("select ID, EntityType, EntityID, Action, OldValue, NewValue, Actor, RequestID, PrevHash, Hash, CreateDate from AuditLog where Hash is not null and ID > ? order by ID limit ?")
+	columns := []string{"ID", "EntityType", "EntityID", "Action", "OldValue", "NewValue", "Actor", "RequestID", "PrevHash", "Hash", "CreateDate"}
+	created := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	//chain builds n correctly linked rows
+	chain := func(n int) []*models.AuditEntry {
+		var entries []*models.AuditEntry
+		var prev *string
+		for i := 1; i <= n; i++ {
+			entry := models.NewAuditEntry()
+			entry.ID = strconv.Itoa(i)
+			entry.EntityType = models.AuditEntityOrder
+			entry.EntityID = "7"
+			entry.Action = "Accrue"
+			entry.Actor = "test"
+			entry.CreateDate = models.NewTimestamp(created)
+			entry.PrevHash = prev
+			hash := auditHash(entry)
+			entry.Hash = &hash
+			prev = &hash
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+	rows := func(entries ...*models.AuditEntry) *sqlmock.Rows {
+		result := sqlmock.NewRows(columns)
+		for _, e := range entries {
+			result.AddRow(e.ID, e.EntityType, e.EntityID, e.Action, nil, nil, e.Actor, e.RequestID, e.PrevHash, e.Hash, created)
+		}
+		return result
+	}
+	broken := chain(3)
+	tampered := "tampered"
+	broken[2].PrevHash = &tampered
+
+	tests := []struct {
+		name        string
+		entries     []*models.AuditEntry
+		wantValid   bool
+		wantChecked int
+		wantBroken  string
+	}{
+		{"empty log", nil, true, 0, ""},
+		{"chain spanning batches", chain(3), true, 3, ""},
+		{"chain filling whole batches", chain(4), true, 4, ""},
+		{"broken link in a later batch", broken, false, 3, "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditVerifyBatchSize = 2
+			defer func() { auditVerifyBatchSize = 1000 }()
+			mock := mockDB(t)
+			//one statement per batch, until a batch comes back short
+			afterID := "0"
+			for start := 0; ; start += 2 {
+				end := start + 2
+				if end > len(tt.entries) {
+					end = len(tt.entries)
+				}
+				mock.ExpectQuery(batch).WithArgs(afterID, 2).WillReturnRows(rows(tt.entries[start:end]...))
+				if end-start < 2 {
+					break
+				}
+				afterID = tt.entries[end-1].ID
+			}
+
+			verification, err := VerifyAuditChain(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verification.Valid != tt.wantValid || verification.CheckedCount != tt.wantChecked || verification.FirstBrokenID != tt.wantBroken {
+				t.Errorf("VerifyAuditChain() = %+v, want valid %v, %d checked, first broken %q", verification, tt.wantValid, tt.wantChecked, tt.wantBroken)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
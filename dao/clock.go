@@ -0,0 +1,49 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/metabloxStaking/clock"
+)
+
+// SyncFakeClock moves the fake clock, if one is in use, to the offset shared by every instance.
+// The first instance to start records its configured offset as the shared one.
+func SyncFakeClock(ctx context.Context) error {
+	fake, ok := clock.Fake()
+	if !ok {
+		return nil
+	}
+	ctx, done := queryContext(ctx, "SyncFakeClock")
+	defer done()
+	sqlStr := "insert ignore into FakeClock (ID, OffsetNanos) values (1, ?)"
+	_, err := SqlDB.ExecContext(ctx, sqlStr, fake.Offset().Nanoseconds())
+	if err != nil {
+		return err
+	}
+	var offset int64
+	err = SqlDB.GetContext(ctx, &offset, "select OffsetNanos from FakeClock where ID = 1")
+	if err != nil {
+		return err
+	}
+	fake.Sync(time.Duration(offset))
+	return nil
+}
+
+// AdvanceFakeClock moves the shared fake clock offset forward and syncs this instance with it.
+// Other instances pick the change up on their next sync.
+func AdvanceFakeClock(ctx context.Context, d time.Duration) error {
+	_, ok := clock.Fake()
+	if !ok {
+		return nil
+	}
+	ctx, done := queryContext(ctx, "AdvanceFakeClock")
+	defer done()
+	sqlStr := "update FakeClock set OffsetNanos = OffsetNanos + ? where ID = 1"
+	result, err := SqlDB.ExecContext(ctx, sqlStr, d.Nanoseconds())
+	err = expectOneRow(result, err, "fake clock has not been synced yet")
+	if err != nil {
+		return err
+	}
+	return SyncFakeClock(ctx)
+}
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/metabloxStaking/tracing"
+)
+
+// Every dao function runs under its caller's context, so statements are cancelled when the
+// request that issued them goes away. Each operation is also bounded by its own timeout:
+// mysql.queryTimeout for a single statement and mysql.txTimeout for a whole transaction, and is
+// traced as a span named after the operation. The mysql settings only change on restart, so they
+// are read once by InitSql.
+
+const defaultQueryTimeout = 5 * time.Second
+const defaultTxTimeout = 15 * time.Second
+
+var (
+	queryTimeout = defaultQueryTimeout
+	txTimeout    = defaultTxTimeout
+	dbName       string
+)
+
+// loadOperationSettings reads the timeouts and database name that every operation uses
+func loadOperationSettings() {
+	queryTimeout = configuredTimeout("mysql.queryTimeout", defaultQueryTimeout)
+	txTimeout = configuredTimeout("mysql.txTimeout", defaultTxTimeout)
+	dbName = viper.GetString("mysql.dbname")
+}
+
+func configuredTimeout(key string, fallback time.Duration) time.Duration {
+	timeout := viper.GetDuration(key)
+	if timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+func queryContext(ctx context.Context, name string) (context.Context, func()) {
+	return operationContext(ctx, name, queryTimeout)
+}
+
+func txContext(ctx context.Context, name string) (context.Context, func()) {
+	return operationContext(ctx, name, txTimeout)
+}
+
+// operationContext returns the context to run the operation under and a function that ends it.
+// The span is marked as failed if the operation ran out of time or was cancelled.
+func operationContext(ctx context.Context, name string, timeout time.Duration) (context.Context, func()) {
+	ctx, span := tracing.Start(ctx, "dao."+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemMySQL,
+			semconv.DBNameKey.String(dbName),
+			semconv.DBOperationKey.String(name),
+		),
+	)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		tracing.End(span, ctx.Err())
+		cancel()
+	}
+}
@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/metabloxStaking/apperrors"
+)
+
+const mysqlErrDuplicateEntry = 1062
+
+// notFound reports a missing row as a NotFound error for the named entity; other errors are
+// returned unchanged and end up as internal errors
+func notFound(err error, entity string) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperrors.NotFound(entity + " not found")
+	}
+	return err
+}
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
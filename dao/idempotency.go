@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/metabloxStaking/models"
+)
+
+// Idempotency keys are scoped to the caller that sent them, so the same key from two callers
+// refers to two separate requests
+
+func ReserveIdempotencyKey(ctx context.Context, scope, key, fingerprint string) (bool, error) {
+	ctx, done := queryContext(ctx, "ReserveIdempotencyKey")
+	defer done()
+	sqlStr := "insert into IdempotencyKeys (Scope, IdempotencyKey, Fingerprint) values (?, ?, ?)"
+	_, err := SqlDB.ExecContext(ctx, sqlStr, scope, key, fingerprint)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func GetIdempotencyRecord(ctx context.Context, scope, key string) (*models.IdempotencyRecord, error) {
+	ctx, done := queryContext(ctx, "GetIdempotencyRecord")
+	defer done()
+	record := models.NewIdempotencyRecord()
+	sqlStr := "select IdempotencyKey, Fingerprint, StatusCode, ResponseBody, CreateDate from IdempotencyKeys where Scope = ? and IdempotencyKey = ?"
+	err := SqlDB.GetContext(ctx, record, sqlStr, scope, key)
+	if err != nil {
+		return nil, notFound(err, "idempotency key")
+	}
+	return record, nil
+}
+
+func SaveIdempotencyResponse(ctx context.Context, scope, key string, statusCode int, body []byte) error {
+	ctx, done := queryContext(ctx, "SaveIdempotencyResponse")
+	defer done()
+	sqlStr := "update IdempotencyKeys set StatusCode = ?, ResponseBody = ? where Scope = ? and IdempotencyKey = ?"
+	_, err := SqlDB.ExecContext(ctx, sqlStr, statusCode, body, scope, key)
+	return err
+}
+
+func DeleteIdempotencyKey(ctx context.Context, scope, key string) error {
+	ctx, done := queryContext(ctx, "DeleteIdempotencyKey")
+	defer done()
+	sqlStr := "delete from IdempotencyKeys where Scope = ? and IdempotencyKey = ?"
+	_, err := SqlDB.ExecContext(ctx, sqlStr, scope, key)
+	return err
+}
+
+// removes a reservation whose request never completed (e.g. the process died mid-request)
+func ReleaseStaleIdempotencyKey(ctx context.Context, scope, key string, timeoutSeconds int) (bool, error) {
+	ctx, done := queryContext(ctx, "ReleaseStaleIdempotencyKey")
+	defer done()
+	sqlStr := "delete from IdempotencyKeys where Scope = ? and IdempotencyKey = ? and StatusCode = 0 and CreateDate < now() - interval ? second"
+	result, err := SqlDB.ExecContext(ctx, sqlStr, scope, key, timeoutSeconds)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows != 0, nil
+}
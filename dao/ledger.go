@@ -0,0 +1,135 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/models"
+)
+
+// Every movement of MBLX is posted to the ledger as a journal of entries whose amounts sum to zero,
+// in the same database transaction as the change to the order. Per order, the UserPrincipal balance
+// mirrors Orders.Amount and the UserInterestPayable balance mirrors unharvested interest. Orders
+// that were already holding when the ledger was introduced start from an OpeningBalance journal.
+
+// ledgerTolerance absorbs floating point error when comparing sums
+const ledgerTolerance = 1e-6
+
+var errUnbalancedJournal = errors.New("ledger journal does not balance")
+
+// postJournal records a balanced set of entries for an order. Zero amount entries are skipped.
+func postJournal(ctx context.Context, dbTX *sqlx.Tx, orderID, description string, entries ...*models.LedgerEntry) error {
+	var total float64
+	var lines []*models.LedgerEntry
+	for _, entry := range entries {
+		if entry.Amount == 0 {
+			continue
+		}
+		total += entry.Amount
+		lines = append(lines, entry)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	if math.Abs(total) > ledgerTolerance {
+		return errUnbalancedJournal
+	}
+
+	journal := models.NewLedgerJournal()
+	journal.OrderID = orderID
+	journal.Description = description
+	journal.CreateDate = models.NewTimestamp(clock.Now())
+	sqlStr := "insert into LedgerJournals (OrderID, Description, CreateDate) values (:OrderID, :Description, :CreateDate)"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, journal)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		line.JournalID = strconv.FormatInt(id, 10)
+		sqlStr = "insert into LedgerEntries (JournalID, Account, OrderID, Amount) values (:JournalID, :Account, :OrderID, :Amount)"
+		_, err = dbTX.NamedExecContext(ctx, sqlStr, line)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transfer posts a two-sided journal debiting one account and crediting another
+func transfer(ctx context.Context, dbTX *sqlx.Tx, orderID, description, debit, credit string, amount float64) error {
+	return postJournal(ctx, dbTX, orderID, description,
+		models.NewLedgerEntry(debit, orderID, amount),
+		models.NewLedgerEntry(credit, orderID, -amount),
+	)
+}
+
+// postPayout pays principal and unharvested interest out of the treasury
+func postPayout(ctx context.Context, dbTX *sqlx.Tx, orderID, description string, principal, interest float64) error {
+	return postJournal(ctx, dbTX, orderID, description,
+		models.NewLedgerEntry(models.LedgerAccountUserPrincipal, orderID, principal),
+		models.NewLedgerEntry(models.LedgerAccountInterestPayable, orderID, interest),
+		models.NewLedgerEntry(models.LedgerAccountTreasury, orderID, -(principal+interest)),
+	)
+}
+
+func GetLedgerBalances(ctx context.Context) ([]*models.LedgerBalance, error) {
+	ctx, done := queryContext(ctx, "GetLedgerBalances")
+	defer done()
+	var balances []*models.LedgerBalance
+	sqlStr := "select Account, coalesce(sum(Amount), 0) as Balance from LedgerEntries group by Account order by Account"
+	err := SqlDB.SelectContext(ctx, &balances, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	return balances, nil
+}
+
+// CheckLedger proves the ledger's invariants: all entries sum to zero, every journal balances, and
+// each order's liability balances match the principal and unharvested interest on the order
+func CheckLedger(ctx context.Context) (*models.LedgerCheck, error) {
+	ctx, done := queryContext(ctx, "CheckLedger")
+	defer done()
+	check := models.NewLedgerCheck()
+
+	var err error
+	check.Balances, err = GetLedgerBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, balance := range check.Balances {
+		check.Total += balance.Balance
+	}
+
+	sqlStr := "select JournalID from LedgerEntries group by JournalID having abs(sum(Amount)) > ? order by JournalID"
+	err = SqlDB.SelectContext(ctx, &check.UnbalancedJournals, sqlStr, ledgerTolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStr = "select Orders.OrderID from Orders " +
+		"left join (select OrderID, " +
+		"sum(case when Account = 'UserPrincipal' then Amount else 0 end) as Principal, " +
+		"sum(case when Account = 'UserInterestPayable' then Amount else 0 end) as Payable " +
+		"from LedgerEntries group by OrderID) Balances on Balances.OrderID = Orders.OrderID " +
+		"where Orders.Type in ('Holding', 'Matured') and (" +
+		"abs(Orders.Amount + coalesce(Balances.Principal, 0)) > ? or " +
+		"abs(Orders.AccumulatedInterest - Orders.TotalInterestGained + coalesce(Balances.Payable, 0)) > ?) " +
+		"order by Orders.OrderID"
+	err = SqlDB.SelectContext(ctx, &check.MismatchedOrders, sqlStr, ledgerTolerance, ledgerTolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	check.Balanced = math.Abs(check.Total) <= ledgerTolerance && len(check.UnbalancedJournals) == 0 && len(check.MismatchedOrders) == 0
+	return check, nil
+}
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/metabloxStaking/models"
+)
+
+func TestPostJournal(t *testing.T) {
+	journal := regexp.QuoteMeta("insert into LedgerJournals")
+	entry := regexp.QuoteMeta("insert into LedgerEntries")
+	line := func(account string, amount float64) *models.LedgerEntry {
+		return models.NewLedgerEntry(account, "7", amount)
+	}
+
+	tests := []struct {
+		name        string
+		entries     []*models.LedgerEntry
+		wantErr     error
+		wantEntries int
+	}{
+		{
+			name:        "balanced transfer",
+			entries:     []*models.LedgerEntry{line(models.LedgerAccountTreasury, 100), line(models.LedgerAccountUserPrincipal, -100)},
+			wantEntries: 2,
+		},
+		{
+			name: "payout with zero interest skips the zero line",
+			entries: []*models.LedgerEntry{
+				line(models.LedgerAccountUserPrincipal, 100),
+				line(models.LedgerAccountInterestPayable, 0),
+				line(models.LedgerAccountTreasury, -100),
+			},
+			wantEntries: 2,
+		},
+		{
+			name:        "floating point error within tolerance",
+			entries:     []*models.LedgerEntry{line(models.LedgerAccountInterestExpense, 0.1+0.2), line(models.LedgerAccountInterestPayable, -0.3)},
+			wantEntries: 2,
+		},
+		{
+			name:    "unbalanced",
+			entries: []*models.LedgerEntry{line(models.LedgerAccountTreasury, 100), line(models.LedgerAccountUserPrincipal, -99)},
+			wantErr: errUnbalancedJournal,
+		},
+		{
+			name:    "nothing to post",
+			entries: []*models.LedgerEntry{line(models.LedgerAccountTreasury, 0), line(models.LedgerAccountUserPrincipal, 0)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			mock.ExpectBegin()
+			if tt.wantEntries > 0 {
+				mock.ExpectExec(journal).WillReturnResult(sqlmock.NewResult(3, 1))
+				for i := 0; i < tt.wantEntries; i++ {
+					mock.ExpectExec(entry).WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+				}
+			}
+			mock.ExpectRollback()
+
+			dbTX, err := SqlDB.Beginx()
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = postJournal(context.Background(), dbTX, "7", "Test", tt.entries...)
+			dbTX.Rollback()
+			if err != tt.wantErr {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			for _, entry := range tt.entries {
+				if entry.Amount != 0 && tt.wantEntries > 0 && entry.JournalID != "3" {
+					t.Errorf("%s entry posted to journal %q, want 3", entry.Account, entry.JournalID)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
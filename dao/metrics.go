@@ -0,0 +1,89 @@
+package dao
+
+import (
+	"context"
+
+	"github.com/metabloxStaking/models"
+)
+
+// GetProductTVL returns the principal staked in holding and matured orders of each product
+func GetProductTVL(ctx context.Context) (map[string]float64, error) {
+	ctx, done := queryContext(ctx, "GetProductTVL")
+	defer done()
+	var rows []struct {
+		ProductID string  `db:"ProductID"`
+		Total     float64 `db:"Total"`
+	}
+	sqlStr := "select ProductID, coalesce(sum(Amount), 0) as Total from Orders where Type in ('Holding', 'Matured') group by ProductID"
+	err := SqlDB.SelectContext(ctx, &rows, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	tvl := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		tvl[row.ProductID] = row.Total
+	}
+	return tvl, nil
+}
+
+func CountOrdersByStatus(ctx context.Context) (map[string]int, error) {
+	ctx, done := queryContext(ctx, "CountOrdersByStatus")
+	defer done()
+	var rows []struct {
+		Type  string `db:"Type"`
+		Count int    `db:"Count"`
+	}
+	sqlStr := "select Type, count(*) as Count from Orders group by Type"
+	err := SqlDB.SelectContext(ctx, &rows, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Type] = row.Count
+	}
+	return counts, nil
+}
+
+func CountQueuedPayouts(ctx context.Context) (int, error) {
+	ctx, done := queryContext(ctx, "CountQueuedPayouts")
+	defer done()
+	var count int
+	sqlStr := "select count(*) from PayoutQueue where Status = 'Queued'"
+	err := SqlDB.GetContext(ctx, &count, sqlStr)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetOldestAccrual returns the earliest point up to which a Holding order has accrued interest,
+// taken from its latest OrderInterest row or its buy-in if it has none, or a zero Timestamp if
+// there are no Holding orders
+func GetOldestAccrual(ctx context.Context) (models.Timestamp, error) {
+	ctx, done := queryContext(ctx, "GetOldestAccrual")
+	defer done()
+	var oldest models.Timestamp
+	sqlStr := "select min(coalesce(" +
+		"(select max(Time) from OrderInterest where OrderInterest.OrderID = Orders.OrderID), " +
+		"(select min(CreateDate) from TXInfo where TXInfo.OrderID = Orders.OrderID and TXInfo.TXType = 'BuyIn'))) " +
+		"from Orders where Type = 'Holding'"
+	err := SqlDB.GetContext(ctx, &oldest, sqlStr)
+	if err != nil {
+		return models.Timestamp{}, err
+	}
+	return oldest, nil
+}
+
+// GetHoldingOrderIDs returns the IDs of every Holding order
+func GetHoldingOrderIDs(ctx context.Context) ([]string, error) {
+	ctx, done := queryContext(ctx, "GetHoldingOrderIDs")
+	defer done()
+	var ids []string
+	sqlStr := "select OrderID from Orders where Type = 'Holding' order by OrderID"
+	err := SqlDB.SelectContext(ctx, &ids, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/migrations"
+)
+
+const migrationLock = "metabloxStaking.migrate"
+
+// migrationLockTimeout is how long, in seconds, to wait for another instance to finish migrating
+const migrationLockTimeout = 300
+
+// Migrate applies every migration that has not been applied yet, in file name order. A named lock
+// stops instances that start together from applying the same file twice. Backfills can take a
+// while, so only ctx bounds the run.
+func Migrate(ctx context.Context) error {
+	conn, err := SqlDB.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked int
+	err = conn.GetContext(ctx, &locked, "select get_lock(?, ?)", migrationLock, migrationLockTimeout)
+	if err != nil {
+		return err
+	}
+	if locked != 1 {
+		return errors.New("timed out waiting for another instance to finish migrating")
+	}
+	defer conn.ExecContext(context.Background(), "select release_lock(?)", migrationLock)
+
+	sqlStr := "create table if not exists SchemaMigrations (Version varchar(255) not null, AppliedDate datetime not null default current_timestamp, primary key (Version)) engine = InnoDB"
+	_, err = conn.ExecContext(ctx, sqlStr)
+	if err != nil {
+		return err
+	}
+	var versions []string
+	err = conn.SelectContext(ctx, &versions, "select Version from SchemaMigrations")
+	if err != nil {
+		return err
+	}
+	applied := make(map[string]bool, len(versions))
+	for _, version := range versions {
+		applied[version] = true
+	}
+
+	names, err := fs.Glob(migrations.Files, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if applied[name] {
+			continue
+		}
+		data, err := migrations.Files.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		for _, statement := range splitStatements(string(data)) {
+			_, err = conn.ExecContext(ctx, statement)
+			if err != nil {
+				return errors.New("migration " + name + " failed: " + err.Error())
+			}
+		}
+		_, err = conn.ExecContext(ctx, "insert into SchemaMigrations (Version) values (?)", name)
+		if err != nil {
+			return err
+		}
+		logger.Info("applied migration " + name)
+	}
+	return nil
+}
+
+// splitStatements breaks a migration into statements, each ended by a semicolon at the end of a
+// line. Comment lines are dropped.
+func splitStatements(script string) []string {
+	var statements []string
+	var current []string
+	scanner := bufio.NewScanner(strings.NewReader(script))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if strings.HasSuffix(line, ";") {
+			current = append(current, strings.TrimSuffix(line, ";"))
+			statements = append(statements, strings.Join(current, "\n"))
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		statements = append(statements, strings.Join(current, "\n"))
+	}
+	return statements
+}
@@ -0,0 +1,105 @@
+package dao
+
+import (
+	"fmt"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/metabloxStaking/migrations"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{
+			name:   "single statement",
+			script: "create table A (ID int);\n",
+			want:   []string{"create table A (ID int)"},
+		},
+		{
+			name:   "statement over several lines",
+			script: "create table A (\n  ID int\n);\n",
+			want:   []string{"create table A (\n  ID int\n)"},
+		},
+		{
+			name:   "comments and blank lines are dropped",
+			script: "-- a comment\n\ncreate table A (ID int);\n  -- indented comment\nalter table A add B int;\n",
+			want:   []string{"create table A (ID int)", "alter table A add B int"},
+		},
+		{
+			name:   "semicolon inside a line does not end the statement",
+			script: "insert into A (Text) values ('a;b');\n",
+			want:   []string{"insert into A (Text) values ('a;b')"},
+		},
+		{
+			name:   "trailing whitespace after the semicolon",
+			script: "create table A (ID int);  \r\ncreate table B (ID int);",
+			want:   []string{"create table A (ID int)", "create table B (ID int)"},
+		},
+		{
+			name:   "trigger with a single statement body",
+			script: "create trigger T before delete on A for each row\n  signal sqlstate '45000' set message_text = 'no';\n",
+			want:   []string{"create trigger T before delete on A for each row\n  signal sqlstate '45000' set message_text = 'no'"},
+		},
+		{
+			name:   "last statement without a semicolon",
+			script: "create table A (ID int)\n",
+			want:   []string{"create table A (ID int)"},
+		},
+		{
+			name:   "empty script",
+			script: "-- nothing to do\n",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.script)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationFiles(t *testing.T) {
+	names, err := fs.Glob(migrations.Files, "*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) == 0 {
+		t.Fatal("no migrations found")
+	}
+	sort.Strings(names)
+
+	pattern := regexp.MustCompile(`^(\d{4})_[a-z0-9_]+\.sql$`)
+	seen := make(map[string]string)
+	for i, name := range names {
+		match := pattern.FindStringSubmatch(name)
+		if match == nil {
+			t.Errorf("%s: name must be a four digit number followed by a lower case description", name)
+			continue
+		}
+		if other, ok := seen[match[1]]; ok {
+			t.Errorf("%s and %s share the same number", other, name)
+		}
+		seen[match[1]] = name
+		if want := fmt.Sprintf("%04d", i+1); match[1] != want {
+			t.Errorf("%s: expected migration number %s; numbers must not skip", name, want)
+		}
+
+		data, err := migrations.Files.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(splitStatements(string(data))) == 0 {
+			t.Errorf("%s has no statements", name)
+		}
+	}
+}
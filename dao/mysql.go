@@ -1,23 +1,33 @@
 package dao
 
 import (
-	"errors"
+	"context"
+	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	logger "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
 	"github.com/metabloxStaking/models"
 )
 
 var SqlDB *sqlx.DB
 
+const insertTXInfoStr = "insert into TXInfo (OrderID, TXCurrencyType, TXType, TXHash, Principal, Interest, UserAddress, RedeemableTime, CreateDate) values (:OrderID, :TXCurrencyType, :TXType, :TXHash, :Principal, :Interest, :UserAddress, :RedeemableTime, :CreateDate)"
+
 func InitSql() error {
 	var err error
+	loadOperationSettings()
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+	//all DATETIME columns hold UTC; parseTime scans them into time.Time and time_zone makes the
+	//session's now() and column defaults agree with the service
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true&loc=UTC&time_zone=%%27%%2B00%%3A00%%27",
 		viper.GetString("mysql.user"),
 		viper.GetString("mysql.password"),
 		viper.GetString("mysql.host"),
@@ -49,22 +59,38 @@ func InitSql() error {
 	return nil
 }
 
-func GetProductInfoByID(productID string) (*models.ProductDetails, error) {
+// stampTX dates a transaction by the service clock rather than the database's, so that a fake
+// clock applies to recorded transactions as well
+func stampTX(tx *models.TXInfo) {
+	tx.CreateDate = models.NewTimestamp(clock.Now())
+}
+
+func GetProductInfoByID(ctx context.Context, productID string) (*models.ProductDetails, error) {
+	ctx, done := queryContext(ctx, "GetProductInfoByID")
+	defer done()
 	product := models.NewProductDetails()
 
-	sqlStr := "select ID, ProductName, MinOrderValue, TopUpLimit, LockUpPeriod, Status, MinRedeemValue from StakingProducts where ID = ?"
-	err := SqlDB.Get(product, sqlStr, productID)
+	sqlStr := "select ID, ProductName, MinOrderValue, TopUpLimit, LockUpPeriod, Term, State, MinRedeemValue from StakingProducts where ID = ?"
+	err := SqlDB.GetContext(ctx, product, sqlStr, productID)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "product")
 	}
 
 	return product, nil
 }
 
-func GetAllProductInfo() ([]*models.ProductDetails, error) {
+// GetAllProductInfo lists products, optionally only those in the given lifecycle state
+func GetAllProductInfo(ctx context.Context, state string) ([]*models.ProductDetails, error) {
+	ctx, done := queryContext(ctx, "GetAllProductInfo")
+	defer done()
 	var products []*models.ProductDetails
-	sqlStr := "select ID, ProductName, MinOrderValue, TopUpLimit, LockUpPeriod, Status, MinRedeemValue from StakingProducts"
-	rows, err := SqlDB.Queryx(sqlStr)
+	sqlStr := "select ID, ProductName, MinOrderValue, TopUpLimit, LockUpPeriod, Term, State, MinRedeemValue from StakingProducts"
+	var args []interface{}
+	if state != "" {
+		sqlStr += " where State = ?"
+		args = append(args, state)
+	}
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -81,25 +107,48 @@ func GetAllProductInfo() ([]*models.ProductDetails, error) {
 	return products, err
 }
 
-func CreateOrder(order *models.Order) (int, error) {
-	sqlStr := "insert into Orders (ProductID, UserDID, Type, Term, PaymentAddress, Amount, UserAddress) values (:ProductID, :UserDID, :Type, :Term, :PaymentAddress, :Amount, :UserAddress)"
-	result, err := SqlDB.NamedExec(sqlStr, order)
+func CreateOrder(ctx context.Context, actor *models.Actor, order *models.Order) (int, error) {
+	ctx, done := txContext(ctx, "CreateOrder")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	sqlStr := "insert into Orders (ProductID, UserDID, Type, Term, PaymentAddress, Amount, UserAddress, AutoRenew, CompoundInterest) values (:ProductID, :UserDID, :Type, :Term, :PaymentAddress, :Amount, :UserAddress, :AutoRenew, :CompoundInterest)"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, order)
 	if err != nil {
+		dbTX.Rollback()
 		return 0, err
 	}
 
 	id, err := result.LastInsertId()
 	if err != nil {
+		dbTX.Rollback()
 		return 0, err
 	}
 
-	return int(id), nil
+	created := models.NewOrder()
+	err = dbTX.GetContext(ctx, created, "select * from Orders where OrderID = ?", id)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	err = insertAudit(ctx, dbTX, actor, models.AuditEntityOrder, created.OrderID, "Create", nil, created)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+
+	return int(id), dbTX.Commit()
 }
 
-func CheckIfTXExists(txHash string) (bool, error) {
+func CheckIfTXExists(ctx context.Context, txHash string) (bool, error) {
+	ctx, done := queryContext(ctx, "CheckIfTXExists")
+	defer done()
 	var count int
 	sqlStr := "select count(*) from TXInfo where TXHash = ?"
-	err := SqlDB.Get(&count, sqlStr, txHash)
+	err := SqlDB.GetContext(ctx, &count, sqlStr, txHash)
 	if err != nil {
 		return false, err
 	}
@@ -107,183 +156,257 @@ func CheckIfTXExists(txHash string) (bool, error) {
 	return (count != 0), nil
 }
 
-func GetTXCreateDate(txHash string) (string, error) {
-	var date string
-	sqlStr := "select unix_timestamp(CreateDate) from TXInfo where TXHash = ?"
-	err := SqlDB.Get(&date, sqlStr, txHash)
+func GetTXCreateDate(ctx context.Context, txHash string) (models.Timestamp, error) {
+	ctx, done := queryContext(ctx, "GetTXCreateDate")
+	defer done()
+	var date models.Timestamp
+	sqlStr := "select CreateDate from TXInfo where TXHash = ?"
+	err := SqlDB.GetContext(ctx, &date, sqlStr, txHash)
 	if err != nil {
-		return "", err
+		return models.Timestamp{}, notFound(err, "transaction")
 	}
 	return date, nil
 }
 
-func GetStakingRecords(did string) ([]*models.StakingRecord, error) {
+func GetStakingRecords(ctx context.Context, did string, opts *models.ListOptions) ([]*models.StakingRecord, string, error) {
+	ctx, done := queryContext(ctx, "GetStakingRecords")
+	defer done()
 	var records []*models.StakingRecord
-	sqlStr := "select Orders.OrderID, Orders.ProductID, Orders.Type, Orders.Term, TXInfo.CreateDate, Orders.Amount, TXInfo.TXCurrencyType, TXInfo.RedeemableTime from Orders join TXInfo on TXInfo.OrderID = Orders.OrderID where Orders.UserDID = ? and TXInfo.TXType = 'BuyIn' and Orders.Type != 'Pending'"
-	rows, err := SqlDB.Queryx(sqlStr, did)
+	query := newListQuery("Orders.UserDID = ? and TXInfo.TXType = 'BuyIn' and Orders.Type != 'Pending'", did)
+	if opts.Status != "" {
+		query.where("Orders.Type = ?", opts.Status)
+	}
+	if opts.ProductID != "" {
+		query.where("Orders.ProductID = ?", opts.ProductID)
+	}
+	query.whereDateRange("TXInfo.CreateDate", opts)
+	sqlStr, args := query.build("select Orders.OrderID, Orders.ProductID, Orders.Type, Orders.Term, TXInfo.CreateDate, Orders.Amount, TXInfo.TXCurrencyType, Orders.LockUpEndDate, Orders.MaturityDate as RedeemableTime, Orders.ClosureWindowEnd, (select coalesce(sum(TopUps.Principal), 0) from TXInfo TopUps where TopUps.OrderID = Orders.OrderID and TopUps.TXType = 'TopUp') as TopUpAmount from Orders join TXInfo on TXInfo.OrderID = Orders.OrderID", "Orders.OrderID", opts)
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		record := models.NewStakingRecord()
 		err = rows.StructScan(record)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		records = append(records, record)
 	}
-	return records, nil
+	next := nextKey(len(records), opts, func(i int) string { return records[i].OrderID })
+	if next != "" {
+		records = records[:opts.Limit]
+	}
+	return records, next, rows.Err()
 }
 
-func GetInterestInfoByOrderID(id string) (*models.OrderInterestInfo, error) {
+func GetInterestInfoByOrderID(ctx context.Context, id string) (*models.OrderInterestInfo, error) {
+	ctx, done := queryContext(ctx, "GetInterestInfoByOrderID")
+	defer done()
 	info := models.NewOrderInterestInfo()
 	sqlStr := "select AccumulatedInterest, TotalInterestGained from Orders where OrderID = ?"
-	err := SqlDB.Get(info, sqlStr, id)
+	err := SqlDB.GetContext(ctx, info, sqlStr, id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "order")
 	}
 	return info, nil
 }
 
-func PrepareGetInterestByOrderID() (*sqlx.Stmt, error) {
+func PrepareGetInterestByOrderID(ctx context.Context) (*sqlx.Stmt, error) {
+	ctx, done := queryContext(ctx, "PrepareGetInterestByOrderID")
+	defer done()
 	sqlStr := "select AccumulatedInterest, TotalInterestGained from Orders where OrderID = ?"
-	stmt, err := SqlDB.Preparex(sqlStr)
+	stmt, err := SqlDB.PreparexContext(ctx, sqlStr)
 	if err != nil {
 		return nil, err
 	}
 	return stmt, nil
 }
 
-func ExecuteGetInterestStmt(id string, stmt *sqlx.Stmt) (*models.OrderInterestInfo, error) {
+func ExecuteGetInterestStmt(ctx context.Context, id string, stmt *sqlx.Stmt) (*models.OrderInterestInfo, error) {
+	ctx, done := queryContext(ctx, "ExecuteGetInterestStmt")
+	defer done()
 	info := models.NewOrderInterestInfo()
-	err := stmt.Get(info, id)
+	err := stmt.GetContext(ctx, info, id)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "order")
 	}
 	return info, nil
 }
 
-func GetTransactionsByOrderID(orderID string) ([]*models.TXInfo, error) {
-	var transactions []*models.TXInfo
-	sqlStr := "select PaymentNo, OrderID, TXCurrencyType, TXType, TXHash, Principal, Interest, UserAddress, CreateDate, RedeemableTime from TXInfo where OrderID = ?"
-	rows, err := SqlDB.Queryx(sqlStr, orderID)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		tx := models.NewTXInfo()
-		err = rows.StructScan(tx)
-		if err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, tx)
-	}
-	return transactions, err
+func GetTransactionsByOrderID(ctx context.Context, orderID string, opts *models.ListOptions) ([]*models.TXInfo, string, error) {
+	query := newListQuery("TXInfo.OrderID = ?", orderID)
+	return getTransactions(ctx, query, opts)
+}
+
+func GetTransactionsByUserDID(ctx context.Context, userDID string, opts *models.ListOptions) ([]*models.TXInfo, string, error) {
+	query := newListQuery("Orders.UserDID = ?", userDID)
+	return getTransactions(ctx, query, opts)
 }
 
-func GetTransactionsByUserDID(userDID string) ([]*models.TXInfo, error) {
+func getTransactions(ctx context.Context, query *listQuery, opts *models.ListOptions) ([]*models.TXInfo, string, error) {
+	ctx, done := queryContext(ctx, "getTransactions")
+	defer done()
 	var transactions []*models.TXInfo
-	sqlStr := "select TXInfo.PaymentNo, TXInfo.OrderID, TXInfo.TXCurrencyType, TXInfo.TXType, TXInfo.TXHash, TXInfo.Principal, TXInfo.Interest, TXInfo.UserAddress, TXInfo.CreateDate, TXInfo.RedeemableTime from TXInfo join Orders on Orders.OrderID = TXInfo.OrderID where Orders.UserDID = ?"
-	rows, err := SqlDB.Queryx(sqlStr, userDID)
+	if opts.Status != "" {
+		query.where("Orders.Type = ?", opts.Status)
+	}
+	if opts.TXType != "" {
+		query.where("TXInfo.TXType = ?", opts.TXType)
+	}
+	if opts.ProductID != "" {
+		query.where("Orders.ProductID = ?", opts.ProductID)
+	}
+	query.whereDateRange("TXInfo.CreateDate", opts)
+	sqlStr, args := query.build("select TXInfo.PaymentNo, TXInfo.OrderID, TXInfo.TXCurrencyType, TXInfo.TXType, TXInfo.TXHash, TXInfo.Principal, TXInfo.Interest, TXInfo.UserAddress, TXInfo.CreateDate, TXInfo.RedeemableTime from TXInfo join Orders on Orders.OrderID = TXInfo.OrderID", "TXInfo.PaymentNo", opts)
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		tx := models.NewTXInfo()
 		err = rows.StructScan(tx)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		transactions = append(transactions, tx)
 	}
-	return transactions, err
+	next := nextKey(len(transactions), opts, func(i int) string { return transactions[i].PaymentNo })
+	if next != "" {
+		transactions = transactions[:opts.Limit]
+	}
+	return transactions, next, rows.Err()
 }
 
-func GetOrderInterestByID(orderID string) ([]*models.OrderInterest, error) {
+func GetOrderInterestByID(ctx context.Context, orderID string, opts *models.ListOptions) ([]*models.OrderInterest, string, error) {
+	ctx, done := queryContext(ctx, "GetOrderInterestByID")
+	defer done()
 	var interests []*models.OrderInterest
-	sqlStr := "select * from OrderInterest where OrderID = ?"
-	rows, err := SqlDB.Queryx(sqlStr, orderID)
+	query := newListQuery("OrderID = ?", orderID)
+	query.whereDateRange("Time", opts)
+	sqlStr, args := query.build("select ID, OrderID, Time, APY, InterestGain, TotalInterestGain from OrderInterest", "ID", opts)
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		interest := models.NewOrderInterest()
 		err = rows.StructScan(interest)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		interests = append(interests, interest)
 	}
-	return interests, nil
-}
-
-func RedeemInterestByOrderID(orderID string) error {
-	sqlStr := "update OrderInterest set TotalInterestGain = 0 where OrderID = ? order by ID desc limit 1"
-	_, err := SqlDB.Exec(sqlStr, orderID)
-	if err != nil {
-		return err
+	next := nextKey(len(interests), opts, func(i int) string { return interests[i].ID })
+	if next != "" {
+		interests = interests[:opts.Limit]
 	}
-	return nil
+	return interests, next, rows.Err()
 }
 
-func GetOrderByID(orderID string) (*models.Order, error) {
+func GetOrderByID(ctx context.Context, orderID string) (*models.Order, error) {
+	ctx, done := queryContext(ctx, "GetOrderByID")
+	defer done()
 	order := models.NewOrder()
 	sqlStr := "select * from Orders where OrderID = ?"
-	err := SqlDB.Get(order, sqlStr, orderID)
+	err := SqlDB.GetContext(ctx, order, sqlStr, orderID)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "order")
 	}
 	return order, nil
 }
 
-func GetOrderRedeemableDate(orderID string) (string, error) {
-	var redeemableDate string
-	sqlStr := "select RedeemableTime from TXInfo where OrderID = ? and TXType = 'BuyIn'"
-	err := SqlDB.Get(&redeemableDate, sqlStr, orderID)
+func GetOrderRedeemableDate(ctx context.Context, orderID string) (models.Timestamp, error) {
+	ctx, done := queryContext(ctx, "GetOrderRedeemableDate")
+	defer done()
+	var redeemableDate models.Timestamp
+	sqlStr := "select MaturityDate from Orders where OrderID = ?"
+	err := SqlDB.GetContext(ctx, &redeemableDate, sqlStr, orderID)
 	if err != nil {
-		return "", err
+		return models.Timestamp{}, notFound(err, "order")
+	}
+	if redeemableDate.IsZero() {
+		return models.Timestamp{}, apperrors.Conflict("order has not started its term")
 	}
 	return redeemableDate, nil
 }
 
-func GetUserAddressByOrderID(orderID string) (string, error) {
+func GetUserAddressByOrderID(ctx context.Context, orderID string) (string, error) {
+	ctx, done := queryContext(ctx, "GetUserAddressByOrderID")
+	defer done()
 	var userAddress string
 	sqlStr := "select UserAddress from Orders where OrderID = ?"
-	err := SqlDB.Get(&userAddress, sqlStr, orderID)
+	err := SqlDB.GetContext(ctx, &userAddress, sqlStr, orderID)
 	if err != nil {
-		return "", err
+		return "", notFound(err, "order")
 	}
 	return userAddress, nil
 }
 
-func GetMinimumInterestByOrderID(orderID string) (int, error) {
+func GetMinimumInterestByOrderID(ctx context.Context, orderID string) (int, error) {
+	ctx, done := queryContext(ctx, "GetMinimumInterestByOrderID")
+	defer done()
 	var minInterest int
 	sqlStr := "select StakingProducts.MinRedeemValue from StakingProducts join Orders on StakingProducts.ID = Orders.ProductID where Orders.OrderID = ?"
-	err := SqlDB.Get(&minInterest, sqlStr, orderID)
+	err := SqlDB.GetContext(ctx, &minInterest, sqlStr, orderID)
 	if err != nil {
-		return 0, err
+		return 0, notFound(err, "order")
 	}
 
 	return minInterest, nil
 }
 
-func UploadTransaction(tx *models.TXInfo) error {
-	sqlStr := "insert into TXInfo (OrderID, TXCurrencyType, TXType, TXHash, Principal, Interest, UserAddress, RedeemableTime) values (:OrderID, :TXCurrencyType, :TXType, :TXHash, :Principal, :Interest, :UserAddress, :RedeemableTime)"
-	_, err := SqlDB.NamedExec(sqlStr, tx)
+var ErrBuyinLimitExceeded = apperrors.Conflict("buy-in would exceed the product's principal limit")
+
+var ErrBelowMinOrderValue = apperrors.Validation("amount is below the product's minimum order value")
+
+// SubmitBuyin moves a Pending order to Holding and stores the term dates set on the order. The
+// product is locked while its minimum order value and principal limit are checked, so that
+// concurrent buy-ins and top-ups cannot together take it over the limit.
+func SubmitBuyin(ctx context.Context, actor *models.Actor, tx *models.TXInfo, order *models.Order) error {
+	ctx, done := txContext(ctx, "SubmitBuyin")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func SubmitBuyin(tx *models.TXInfo) error {
-	dbTX, err := SqlDB.Beginx()
+	var minOrderValue, limit float64
+	sqlStr := "select StakingProducts.MinOrderValue, StakingProducts.TopUpLimit from StakingProducts join Orders on StakingProducts.ID = Orders.ProductID where Orders.OrderID = ? for update"
+	err = dbTX.QueryRowxContext(ctx, sqlStr, order.OrderID).Scan(&minOrderValue, &limit)
 	if err != nil {
+		dbTX.Rollback()
+		return notFound(err, "order")
+	}
+	old, err := lockOrder(ctx, dbTX, order.OrderID)
+	if err != nil {
+		dbTX.Rollback()
 		return err
 	}
-	sqlStr := "update Orders set Type = 'Holding' where OrderID = ?"
-	result, err := dbTX.Exec(sqlStr, tx.OrderID)
+	if old.Amount < minOrderValue {
+		dbTX.Rollback()
+		return ErrBelowMinOrderValue
+	}
+	var total float64
+	sqlStr = "select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'"
+	err = dbTX.GetContext(ctx, &total, sqlStr, old.ProductID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if total+old.Amount > limit {
+		dbTX.Rollback()
+		return ErrBuyinLimitExceeded
+	}
+
+	sqlStr = "update Orders set Type = 'Holding', Term = :Term, LockUpEndDate = :LockUpEndDate, MaturityDate = :MaturityDate, ClosureWindowEnd = :ClosureWindowEnd where OrderID = :OrderID and Type = 'Pending'"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, order)
 	if err != nil {
 		dbTX.Rollback()
 		return err
@@ -295,41 +418,569 @@ func SubmitBuyin(tx *models.TXInfo) error {
 	}
 	if rows == 0 {
 		dbTX.Rollback()
-		return errors.New("failed to update order status; it may not exist, or it may already be holding")
+		return apperrors.Conflict("failed to update order status; it may not exist, or it may already be holding")
+	}
+	err = auditOrder(ctx, dbTX, actor, tx.TXType, old)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = transfer(ctx, dbTX, order.OrderID, tx.TXType, models.LedgerAccountTreasury, models.LedgerAccountUserPrincipal, old.Amount)
+	if err != nil {
+		dbTX.Rollback()
+		return err
 	}
 
-	sqlStr = "insert into TXInfo (OrderID, TXCurrencyType, TXType, TXHash, Principal, Interest, UserAddress, RedeemableTime) values (:OrderID, :TXCurrencyType, :TXType, :TXHash, :Principal, :Interest, :UserAddress, :RedeemableTime)"
-	_, err = dbTX.NamedExec(sqlStr, tx)
+	err = insertTX(ctx, dbTX, actor, tx)
 	if err != nil {
 		dbTX.Rollback()
 		return err
 	}
-	dbTX.Commit()
-	return nil
+	return dbTX.Commit()
 }
 
-func GetTotalInterestGained(id string) (float64, error) {
+func GetTotalInterestGained(ctx context.Context, id string) (float64, error) {
+	ctx, done := queryContext(ctx, "GetTotalInterestGained")
+	defer done()
 	var interest float64
 	sqlStr := "select TotalInterestGained from Orders where OrderID = ?"
-	err := SqlDB.Get(&interest, sqlStr, id)
+	err := SqlDB.GetContext(ctx, &interest, sqlStr, id)
 	if err != nil {
-		return 0, err
+		return 0, notFound(err, "order")
 	}
 	return interest, nil
 }
 
-func HarvestOrderInterest(id string) error {
-	sqlStr := "update Orders set TotalInterestGained = AccumulatedInterest where OrderID = ?"
-	_, err := SqlDB.Query(sqlStr, id)
-	return err
+// HarvestInterest claims an order's unharvested interest and records its payout on tx. The claim
+// only succeeds while there is interest left to harvest, so of two concurrent harvests only one
+// gets through. The payout is sent afterwards and its hash added with SetTXHash.
+func HarvestInterest(ctx context.Context, actor *models.Actor, tx *models.TXInfo) error {
+	sqlStr := "update Orders set TotalInterestGained = AccumulatedInterest where OrderID = ? and Type in ('Holding', 'Matured') and AccumulatedInterest > TotalInterestGained"
+	return updateOrderType(ctx, actor, tx.OrderID, tx.TXType, sqlStr, "no interest to harvest; the order may not exist, it may be complete, or its interest may already have been harvested", func(ctx context.Context, dbTX *sqlx.Tx, old *models.Order) error {
+		err := zeroLatestInterest(ctx, dbTX, actor, tx.OrderID, tx.TXType)
+		if err != nil {
+			return err
+		}
+		tx.Interest = old.AccumulatedInterest - old.TotalInterestGained
+		tx.UserAddress = old.UserAddress
+		err = postPayout(ctx, dbTX, tx.OrderID, tx.TXType, 0, tx.Interest)
+		if err != nil {
+			return err
+		}
+		return insertTX(ctx, dbTX, actor, tx)
+	})
 }
 
-func GetProductNameForOrder(id string) (string, error) {
+// SetTXHash adds the hash of a payout sent after its transaction was recorded
+func SetTXHash(ctx context.Context, actor *models.Actor, paymentNo, txHash string) error {
+	ctx, done := txContext(ctx, "SetTXHash")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	old := models.NewTXInfo()
+	err = dbTX.GetContext(ctx, old, "select * from TXInfo where PaymentNo = ? for update", paymentNo)
+	if err != nil {
+		dbTX.Rollback()
+		return notFound(err, "transaction")
+	}
+	sqlStr := "update TXInfo set TXHash = ? where PaymentNo = ? and TXHash is null"
+	result, err := dbTX.ExecContext(ctx, sqlStr, txHash, paymentNo)
+	err = expectOneRow(result, err, "transaction already has a hash")
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	updated := *old
+	updated.TXHash = &txHash
+	err = insertAudit(ctx, dbTX, actor, models.AuditEntityTransaction, paymentNo, "Send", old, &updated)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+func GetProductNameForOrder(ctx context.Context, id string) (string, error) {
+	ctx, done := queryContext(ctx, "GetProductNameForOrder")
+	defer done()
 	var name string
 	sqlStr := "select StakingProducts.ProductName from StakingProducts join Orders on StakingProducts.ID = Orders.ProductID where Orders.OrderID = ?"
-	err := SqlDB.Get(&name, sqlStr, id)
+	err := SqlDB.GetContext(ctx, &name, sqlStr, id)
 	if err != nil {
-		return "", err
+		return "", notFound(err, "order")
 	}
 	return name, nil
 }
+
+var ErrTopUpLimitExceeded = apperrors.Conflict("top-up would exceed the product's principal limit")
+
+// Accrue works out the interest to record for an order from its accrual basis, or returns nil if
+// none is due
+type Accrue func(basis *models.AccrualBasis) *models.OrderInterest
+
+// GetAccrualBasis reads what the order's next accrual would be worked out from without locking
+// anything, so it is only good for previews. Accruals are recorded through accrueInterest.
+func GetAccrualBasis(ctx context.Context, orderID string) (*models.AccrualBasis, error) {
+	ctx, done := queryContext(ctx, "GetAccrualBasis")
+	defer done()
+	order := models.NewOrder()
+	sqlStr := "select * from Orders where OrderID = ?"
+	err := SqlDB.GetContext(ctx, order, sqlStr, orderID)
+	if err != nil {
+		return nil, notFound(err, "order")
+	}
+	return getAccrualBasis(ctx, SqlDB, order)
+}
+
+func getAccrualBasis(ctx context.Context, q sqlx.QueryerContext, order *models.Order) (*models.AccrualBasis, error) {
+	basis := models.NewAccrualBasis()
+	basis.Order = order
+	sqlStr := "select DefaultAPY from StakingProducts where ID = ?"
+	err := sqlx.GetContext(ctx, q, &basis.DefaultAPY, sqlStr, order.ProductID)
+	if err != nil {
+		return nil, notFound(err, "product")
+	}
+
+	latest := models.NewOrderInterest()
+	sqlStr = "select ID, OrderID, Time, APY, InterestGain, TotalInterestGain from OrderInterest where OrderID = ? order by ID desc limit 1"
+	err = sqlx.GetContext(ctx, q, latest, sqlStr, order.OrderID)
+	if err == nil {
+		basis.Latest = latest
+		return basis, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	sqlStr = "select CreateDate from TXInfo where OrderID = ? and TXType = 'BuyIn'"
+	err = sqlx.GetContext(ctx, q, &basis.BuyinDate, sqlStr, order.OrderID)
+	if err != nil {
+		return nil, notFound(err, "buy-in transaction")
+	}
+	return basis, nil
+}
+
+// AccrueOrderInterest records the order's next accrual, if one is due
+func AccrueOrderInterest(ctx context.Context, actor *models.Actor, orderID string, accrue Accrue) error {
+	ctx, done := txContext(ctx, "AccrueOrderInterest")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, _, err = accrueInterest(ctx, dbTX, actor, orderID, accrue)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+// accrueInterest brings the order's interest up to date within the transaction. The order row is
+// locked before the latest accrual is read, so accruals of the same order queue up behind each
+// other and each one starts where the previous one ended. It returns the order as it stands
+// afterwards, still locked, along with the accrual recorded, if any.
+func accrueInterest(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, orderID string, accrue Accrue) (*models.Order, *models.OrderInterest, error) {
+	order, err := lockOrder(ctx, dbTX, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	basis, err := getAccrualBasis(ctx, dbTX, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	accrual := accrue(basis)
+	if accrual == nil {
+		return order, nil, nil
+	}
+	err = insertOrderInterest(ctx, dbTX, actor, order, accrual)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err = lockOrder(ctx, dbTX, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, accrual, nil
+}
+
+func insertOrderInterest(ctx context.Context, dbTX *sqlx.Tx, actor *models.Actor, old *models.Order, interest *models.OrderInterest) error {
+	sqlStr := "insert into OrderInterest (OrderID, Time, APY, InterestGain, TotalInterestGain) values (:OrderID, :Time, :APY, :InterestGain, :TotalInterestGain)"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, interest)
+	if isDuplicateEntry(err) {
+		return apperrors.Conflict("interest has already been accrued up to this time")
+	}
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	interest.ID = strconv.FormatInt(id, 10)
+	err = insertAudit(ctx, dbTX, actor, models.AuditEntityOrderInterest, interest.ID, "Accrue", nil, interest)
+	if err != nil {
+		return err
+	}
+
+	sqlStr = "update Orders set AccumulatedInterest = AccumulatedInterest + ? where OrderID = ?"
+	_, err = dbTX.ExecContext(ctx, sqlStr, interest.InterestGain, interest.OrderID)
+	if err != nil {
+		return err
+	}
+	err = auditOrder(ctx, dbTX, actor, "Accrue", old)
+	if err != nil {
+		return err
+	}
+	return transfer(ctx, dbTX, interest.OrderID, "Accrue", models.LedgerAccountInterestExpense, models.LedgerAccountInterestPayable, interest.InterestGain)
+}
+
+func GetProductTotalPrincipal(ctx context.Context, productID string) (float64, error) {
+	ctx, done := queryContext(ctx, "GetProductTotalPrincipal")
+	defer done()
+	var total float64
+	sqlStr := "select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'"
+	err := SqlDB.GetContext(ctx, &total, sqlStr, productID)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// SubmitTopUp records a top-up transaction and raises the order's principal. Interest earned on
+// the old principal is first accrued with accrue, so that accrual restarts from the top-up date.
+func SubmitTopUp(ctx context.Context, actor *models.Actor, tx *models.TXInfo, accrue Accrue) error {
+	ctx, done := txContext(ctx, "SubmitTopUp")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var limit, total float64
+	sqlStr := "select StakingProducts.TopUpLimit from StakingProducts join Orders on StakingProducts.ID = Orders.ProductID where Orders.OrderID = ? for update"
+	err = dbTX.GetContext(ctx, &limit, sqlStr, tx.OrderID)
+	if err != nil {
+		dbTX.Rollback()
+		return notFound(err, "order")
+	}
+	sqlStr = "select coalesce(sum(Amount), 0) from Orders where ProductID = (select ProductID from Orders where OrderID = ?) and Type = 'Holding'"
+	err = dbTX.GetContext(ctx, &total, sqlStr, tx.OrderID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if total+tx.Principal > limit {
+		dbTX.Rollback()
+		return ErrTopUpLimitExceeded
+	}
+
+	old, _, err := accrueInterest(ctx, dbTX, actor, tx.OrderID, accrue)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	sqlStr = "update Orders set Amount = Amount + ? where OrderID = ? and Type = 'Holding'"
+	result, err := dbTX.ExecContext(ctx, sqlStr, tx.Principal, tx.OrderID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if rows == 0 {
+		dbTX.Rollback()
+		return apperrors.Conflict("failed to top up order; it may not exist, or it may not be holding")
+	}
+	err = auditOrder(ctx, dbTX, actor, tx.TXType, old)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = transfer(ctx, dbTX, tx.OrderID, tx.TXType, models.LedgerAccountTreasury, models.LedgerAccountUserPrincipal, tx.Principal)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+
+	err = insertTX(ctx, dbTX, actor, tx)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+// ValidateEarlyRedemption checks that the order, as it stands once locked, may be redeemed early.
+// The order may have been renewed onto a new term since it was quoted.
+type ValidateEarlyRedemption func(order *models.Order) error
+
+// SubmitEarlyRedemption closes a Holding order before the end of its term. Interest is brought up
+// to the redemption time with accrue; penaltyRate of the unharvested interest is forfeited to the
+// product's burned interest and the remainder is paid out with the principal. The amounts to pay
+// are set on tx, and the forfeited interest is returned. The payout is sent afterwards and its
+// hash added with SetTXHash.
+func SubmitEarlyRedemption(ctx context.Context, actor *models.Actor, tx *models.TXInfo, accrue Accrue, validate ValidateEarlyRedemption, penaltyRate float64) (float64, error) {
+	ctx, done := txContext(ctx, "SubmitEarlyRedemption")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	old, _, err := accrueInterest(ctx, dbTX, actor, tx.OrderID, accrue)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	err = validate(old)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	tx.RedeemableTime = old.MaturityDate
+	unharvested := old.AccumulatedInterest - old.TotalInterestGained
+	forfeited := unharvested * penaltyRate
+	tx.Principal = old.Amount
+	tx.Interest = unharvested - forfeited
+
+	sqlStr := "update Orders set Type = 'Complete', TotalInterestGained = AccumulatedInterest where OrderID = ? and Type = 'Holding'"
+	result, err := dbTX.ExecContext(ctx, sqlStr, tx.OrderID)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	if rows == 0 {
+		dbTX.Rollback()
+		return 0, apperrors.Conflict("failed to redeem order; it may not exist, or it may not be holding")
+	}
+	err = auditOrder(ctx, dbTX, actor, tx.TXType, old)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	err = transfer(ctx, dbTX, tx.OrderID, "Penalty", models.LedgerAccountInterestPayable, models.LedgerAccountBurnedInterest, forfeited)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	err = postPayout(ctx, dbTX, tx.OrderID, tx.TXType, old.Amount, tx.Interest)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+
+	err = zeroLatestInterest(ctx, dbTX, actor, tx.OrderID, tx.TXType)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+
+	sqlStr = "update StakingProducts set BurnedInterest = BurnedInterest + ? where ID = (select ProductID from Orders where OrderID = ?)"
+	_, err = dbTX.ExecContext(ctx, sqlStr, forfeited, tx.OrderID)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+
+	err = insertTX(ctx, dbTX, actor, tx)
+	if err != nil {
+		dbTX.Rollback()
+		return 0, err
+	}
+	return forfeited, dbTX.Commit()
+}
+
+func SetOrderRenewal(ctx context.Context, actor *models.Actor, orderID string, autoRenew, compoundInterest bool) error {
+	ctx, done := txContext(ctx, "SetOrderRenewal")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	old, err := lockOrder(ctx, dbTX, orderID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+
+	sqlStr := "update Orders set AutoRenew = ?, CompoundInterest = ? where OrderID = ? and Type in ('Pending', 'Holding')"
+	result, err := dbTX.ExecContext(ctx, sqlStr, autoRenew, compoundInterest, orderID)
+	err = expectOneRow(result, err, "failed to update renewal preference; the order may not exist, or it may already have matured")
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = auditOrder(ctx, dbTX, actor, "SetRenewal", old)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+// returns Holding orders whose closure window ended on or before the given date and that have no queued payout
+func GetOrdersClosedBefore(ctx context.Context, date time.Time) ([]*models.Order, error) {
+	ctx, done := queryContext(ctx, "GetOrdersClosedBefore")
+	defer done()
+	var orders []*models.Order
+	//orders waiting on a queued payout stay as they are until it is paid
+	sqlStr := "select * from Orders where Type = 'Holding' and ClosureWindowEnd <= ? and not exists (select 1 from PayoutQueue where PayoutQueue.OrderID = Orders.OrderID and PayoutQueue.Status = 'Queued')"
+	rows, err := SqlDB.QueryxContext(ctx, sqlStr, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		order := models.NewOrder()
+		err = rows.StructScan(order)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, rows.Err()
+}
+
+// RenewOrder starts a new term on a Holding order using the term dates set on the order. The
+// previous term is closed off with accrue; if compoundInterest is set, all unharvested interest is
+// moved into the principal. The amounts carried into the new term are set on tx.
+func RenewOrder(ctx context.Context, actor *models.Actor, tx *models.TXInfo, order *models.Order, accrue Accrue, compoundInterest bool) error {
+	ctx, done := txContext(ctx, "RenewOrder")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	old, _, err := accrueInterest(ctx, dbTX, actor, order.OrderID, accrue)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	tx.Principal = old.Amount
+	if compoundInterest {
+		tx.Interest = old.AccumulatedInterest - old.TotalInterestGained
+		tx.Principal += tx.Interest
+	}
+	//with nothing to compound the update below would change no rows
+	compoundInterest = compoundInterest && tx.Interest > 0
+	sqlStr := "update Orders set Term = :Term, LockUpEndDate = :LockUpEndDate, MaturityDate = :MaturityDate, ClosureWindowEnd = :ClosureWindowEnd where OrderID = :OrderID and Type = 'Holding'"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, order)
+	err = expectOneRow(result, err, "failed to renew order; it may no longer be holding")
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+
+	if compoundInterest {
+		sqlStr = "update Orders set Amount = Amount + (AccumulatedInterest - TotalInterestGained), TotalInterestGained = AccumulatedInterest where OrderID = ? and Type = 'Holding'"
+		result, err = dbTX.ExecContext(ctx, sqlStr, tx.OrderID)
+		err = expectOneRow(result, err, "failed to compound interest; the order may no longer be holding")
+		if err != nil {
+			dbTX.Rollback()
+			return err
+		}
+		err = zeroLatestInterest(ctx, dbTX, actor, tx.OrderID, tx.TXType)
+		if err != nil {
+			dbTX.Rollback()
+			return err
+		}
+	}
+	err = auditOrder(ctx, dbTX, actor, tx.TXType, old)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if compoundInterest {
+		err = transfer(ctx, dbTX, tx.OrderID, "Compound", models.LedgerAccountInterestPayable, models.LedgerAccountUserPrincipal, old.AccumulatedInterest-old.TotalInterestGained)
+		if err != nil {
+			dbTX.Rollback()
+			return err
+		}
+	}
+
+	err = insertTX(ctx, dbTX, actor, tx)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+func MatureOrder(ctx context.Context, actor *models.Actor, orderID string) error {
+	sqlStr := "update Orders set Type = 'Matured' where OrderID = ? and Type = 'Holding'"
+	return updateOrderType(ctx, actor, orderID, "Mature", sqlStr, "failed to mature order; it may not exist, or it may not be holding", nil)
+}
+
+// RedeemOrder claims a Holding or Matured order for redemption, completing it, and records the
+// payout of its principal and unharvested interest on tx. The order is completed before anything
+// is paid, so of two concurrent redemptions only one gets through. The payout is sent afterwards
+// and its hash added with SetTXHash.
+func RedeemOrder(ctx context.Context, actor *models.Actor, tx *models.TXInfo) error {
+	sqlStr := "update Orders set Type = 'Complete', TotalInterestGained = AccumulatedInterest where OrderID = ? and Type in ('Holding', 'Matured')"
+	return updateOrderType(ctx, actor, tx.OrderID, tx.TXType, sqlStr, "failed to redeem order; it may not exist, or it may already be complete", func(ctx context.Context, dbTX *sqlx.Tx, old *models.Order) error {
+		tx.Principal = old.Amount
+		tx.Interest = old.AccumulatedInterest - old.TotalInterestGained
+		tx.UserAddress = old.UserAddress
+		tx.RedeemableTime = old.MaturityDate
+		err := postPayout(ctx, dbTX, tx.OrderID, tx.TXType, tx.Principal, tx.Interest)
+		if err != nil {
+			return err
+		}
+		return insertTX(ctx, dbTX, actor, tx)
+	})
+}
+
+func updateOrderType(ctx context.Context, actor *models.Actor, orderID, action, sqlStr, conflictMsg string, post func(ctx context.Context, dbTX *sqlx.Tx, old *models.Order) error) error {
+	ctx, done := txContext(ctx, "updateOrderType")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	old, err := lockOrder(ctx, dbTX, orderID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	result, err := dbTX.ExecContext(ctx, sqlStr, orderID)
+	err = expectOneRow(result, err, conflictMsg)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = auditOrder(ctx, dbTX, actor, action, old)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if post != nil {
+		err = post(ctx, dbTX, old)
+		if err != nil {
+			dbTX.Rollback()
+			return err
+		}
+	}
+	return dbTX.Commit()
+}
+
+// Ping checks that the database can be reached before ctx expires
+func Ping(ctx context.Context) error {
+	return SqlDB.PingContext(ctx)
+}
+
+func CloseSql() error {
+	return SqlDB.Close()
+}
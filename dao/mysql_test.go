@@ -0,0 +1,365 @@
+package dao
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+)
+
+var orderColumns = []string{"OrderID", "ProductID", "UserDID", "Type", "Term", "AccumulatedInterest", "TotalInterestGained", "PaymentAddress", "Amount", "UserAddress", "AutoRenew", "CompoundInterest", "LockUpEndDate", "MaturityDate", "ClosureWindowEnd"}
+
+func mockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	SqlDB = sqlx.NewDb(db, "mysql")
+	return mock
+}
+
+func holdingOrderRow(orderID string, amount float64) *sqlmock.Rows {
+	return sqlmock.NewRows(orderColumns).AddRow(orderID, "1", "did:metablox:alice", models.OrderTypeHolding, 30, 0, 0, "", amount, "0xaa", false, false, nil, nil, nil)
+}
+
+func TestAccrueOrderInterest(t *testing.T) {
+	buyin := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	lock := regexp.QuoteMeta("select * from Orders where OrderID = ? for update")
+	apy := regexp.QuoteMeta("select DefaultAPY from StakingProducts where ID = ?")
+	latest := regexp.QuoteMeta("select ID, OrderID, Time, APY, InterestGain, TotalInterestGain from OrderInterest where OrderID = ? order by ID desc limit 1")
+	insert := regexp.QuoteMeta("insert into OrderInterest")
+	latestColumns := []string{"ID", "OrderID", "Time", "APY", "InterestGain", "TotalInterestGain"}
+
+	tests := []struct {
+		name     string
+		expect   func(mock sqlmock.Sqlmock)
+		accrue   Accrue
+		wantKind apperrors.Kind
+		wantErr  bool
+	}{
+		{
+			name: "latest accrual is read after the order is locked",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(holdingOrderRow("7", 100))
+				mock.ExpectQuery(apy).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"DefaultAPY"}).AddRow(10))
+				mock.ExpectQuery(latest).WithArgs("7").WillReturnRows(sqlmock.NewRows(latestColumns).AddRow("3", "7", buyin, 10, 1, 1))
+				mock.ExpectCommit()
+			},
+			accrue: func(basis *models.AccrualBasis) *models.OrderInterest {
+				if basis.Latest == nil || basis.Latest.ID != "3" || basis.DefaultAPY != 10 || basis.Order.Amount != 100 {
+					t.Errorf("unexpected basis %+v", basis)
+				}
+				return nil
+			},
+		},
+		{
+			name: "buy-in date is the baseline before the first accrual",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(holdingOrderRow("7", 100))
+				mock.ExpectQuery(apy).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"DefaultAPY"}).AddRow(10))
+				mock.ExpectQuery(latest).WithArgs("7").WillReturnRows(sqlmock.NewRows(latestColumns))
+				mock.ExpectQuery(regexp.QuoteMeta("select CreateDate from TXInfo where OrderID = ? and TXType = 'BuyIn'")).WithArgs("7").
+					WillReturnRows(sqlmock.NewRows([]string{"CreateDate"}).AddRow(buyin))
+				mock.ExpectCommit()
+			},
+			accrue: func(basis *models.AccrualBasis) *models.OrderInterest {
+				if basis.Latest != nil || !basis.BuyinDate.Equal(buyin) {
+					t.Errorf("unexpected basis %+v", basis)
+				}
+				return nil
+			},
+		},
+		{
+			name: "accrual that raced another is refused",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(holdingOrderRow("7", 100))
+				mock.ExpectQuery(apy).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"DefaultAPY"}).AddRow(10))
+				mock.ExpectQuery(latest).WithArgs("7").WillReturnRows(sqlmock.NewRows(latestColumns).AddRow("3", "7", buyin, 10, 1, 1))
+				mock.ExpectExec(insert).WillReturnError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"})
+				mock.ExpectRollback()
+			},
+			accrue: func(basis *models.AccrualBasis) *models.OrderInterest {
+				accrual := models.NewOrderInterest()
+				accrual.OrderID = basis.Order.OrderID
+				accrual.Time = basis.Latest.Time
+				return accrual
+			},
+			wantErr:  true,
+			wantKind: apperrors.KindConflict,
+		},
+		{
+			name: "missing order",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(sqlmock.NewRows(orderColumns))
+				mock.ExpectRollback()
+			},
+			accrue: func(basis *models.AccrualBasis) *models.OrderInterest {
+				t.Error("accrue called for a missing order")
+				return nil
+			},
+			wantErr:  true,
+			wantKind: apperrors.KindNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			tt.expect(mock)
+			err := AccrueOrderInterest(context.Background(), models.NewActor("test", ""), "7", tt.accrue)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && apperrors.KindOf(err) != tt.wantKind {
+				t.Errorf("error kind = %v, want %v", apperrors.KindOf(err), tt.wantKind)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestClaimBeforePayout(t *testing.T) {
+	lock := regexp.QuoteMeta("select * from Orders where OrderID = ? for update")
+	redeem := regexp.QuoteMeta("update Orders set Type = 'Complete', TotalInterestGained = AccumulatedInterest where OrderID = ? and Type in ('Holding', 'Matured')")
+	harvest := regexp.QuoteMeta("update Orders set TotalInterestGained = AccumulatedInterest where OrderID = ? and Type in ('Holding', 'Matured') and AccumulatedInterest > TotalInterestGained")
+
+	tests := []struct {
+		name  string
+		claim func(ctx context.Context, actor *models.Actor, tx *models.TXInfo) error
+		sql   string
+	}{
+		{"redemption of a completed order", RedeemOrder, redeem},
+		{"harvest with nothing left to harvest", HarvestInterest, harvest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(holdingOrderRow("7", 100))
+			mock.ExpectExec(tt.sql).WithArgs("7").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectRollback()
+
+			tx := models.NewTXInfo()
+			tx.OrderID = "7"
+			err := tt.claim(context.Background(), models.NewActor("test", ""), tx)
+			if apperrors.KindOf(err) != apperrors.KindConflict {
+				t.Errorf("error = %v, want a conflict", err)
+			}
+			if tx.PaymentNo != "" {
+				t.Errorf("transaction recorded as payment %s", tx.PaymentNo)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSubmitBuyinLimits(t *testing.T) {
+	product := regexp.QuoteMeta("select StakingProducts.MinOrderValue, StakingProducts.TopUpLimit from StakingProducts join Orders on StakingProducts.ID = Orders.ProductID where Orders.OrderID = ? for update")
+	lock := regexp.QuoteMeta("select * from Orders where OrderID = ? for update")
+	total := regexp.QuoteMeta("select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'")
+	pendingOrderRow := func(amount float64) *sqlmock.Rows {
+		return sqlmock.NewRows(orderColumns).AddRow("7", "1", "did:metablox:alice", models.OrderTypePending, nil, 0, 0, "", amount, "0xaa", false, false, nil, nil, nil)
+	}
+
+	tests := []struct {
+		name   string
+		amount float64
+		staked float64
+		want   error
+	}{
+		{"below the minimum order value", 5, 0, ErrBelowMinOrderValue},
+		{"over the principal limit", 100, 950, ErrBuyinLimitExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(product).WithArgs("7").WillReturnRows(sqlmock.NewRows([]string{"MinOrderValue", "TopUpLimit"}).AddRow(10, 1000))
+			mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(pendingOrderRow(tt.amount))
+			if tt.want == ErrBuyinLimitExceeded {
+				mock.ExpectQuery(total).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.staked))
+			}
+			mock.ExpectRollback()
+
+			order := models.NewOrder()
+			order.OrderID = "7"
+			err := SubmitBuyin(context.Background(), models.NewActor("test", ""), models.NewTXInfo(), order)
+			if err != tt.want {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRenewOrder(t *testing.T) {
+	lock := regexp.QuoteMeta("select * from Orders where OrderID = ? for update")
+	apy := regexp.QuoteMeta("select DefaultAPY from StakingProducts where ID = ?")
+	latest := regexp.QuoteMeta("select ID, OrderID, Time, APY, InterestGain, TotalInterestGain from OrderInterest where OrderID = ? order by ID desc limit 1")
+	renew := regexp.QuoteMeta("update Orders set Term = ?, LockUpEndDate = ?, MaturityDate = ?, ClosureWindowEnd = ? where OrderID = ? and Type = 'Holding'")
+	compound := regexp.QuoteMeta("update Orders set Amount = Amount + (AccumulatedInterest - TotalInterestGained), TotalInterestGained = AccumulatedInterest where OrderID = ? and Type = 'Holding'")
+	latestColumns := []string{"ID", "OrderID", "Time", "APY", "InterestGain", "TotalInterestGain"}
+	withInterest := func() *sqlmock.Rows {
+		return sqlmock.NewRows(orderColumns).AddRow("7", "1", "did:metablox:alice", models.OrderTypeHolding, 30, 5, 1, "", 100, "0xaa", true, true, nil, nil, nil)
+	}
+	locked := func(mock sqlmock.Sqlmock, order *sqlmock.Rows) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(order)
+		mock.ExpectQuery(apy).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"DefaultAPY"}).AddRow(10))
+		mock.ExpectQuery(latest).WithArgs("7").WillReturnRows(sqlmock.NewRows(latestColumns).AddRow("3", "7", time.Now(), 10, 1, 1))
+	}
+
+	tests := []struct {
+		name          string
+		compound      bool
+		expect        func(mock sqlmock.Sqlmock)
+		wantErr       bool
+		wantPrincipal float64
+	}{
+		{
+			name: "order left holding before the new term was set",
+			expect: func(mock sqlmock.Sqlmock) {
+				locked(mock, holdingOrderRow("7", 100))
+				mock.ExpectExec(renew).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name:     "order left holding before its interest was compounded",
+			compound: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				locked(mock, withInterest())
+				mock.ExpectExec(renew).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(compound).WithArgs("7").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantErr: true,
+		},
+		{
+			name:     "nothing to compound",
+			compound: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				locked(mock, holdingOrderRow("7", 100))
+				mock.ExpectExec(renew).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta("select * from Orders where OrderID = ?")).WithArgs("7").WillReturnRows(holdingOrderRow("7", 100))
+				mock.ExpectExec("insert into AuditLog").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec("insert into TXInfo").WillReturnResult(sqlmock.NewResult(9, 1))
+				mock.ExpectExec("insert into AuditLog").WillReturnResult(sqlmock.NewResult(2, 1))
+				mock.ExpectCommit()
+			},
+			wantPrincipal: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			tt.expect(mock)
+			order := models.NewOrder()
+			order.OrderID = "7"
+			tx := models.NewTXInfo()
+			tx.OrderID = "7"
+			tx.TXType = "Renew"
+			accrue := func(basis *models.AccrualBasis) *models.OrderInterest { return nil }
+
+			err := RenewOrder(context.Background(), models.NewActor("test", ""), tx, order, accrue, tt.compound)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && apperrors.KindOf(err) != apperrors.KindConflict {
+				t.Errorf("error kind = %v, want conflict", apperrors.KindOf(err))
+			}
+			if err == nil && tx.Principal != tt.wantPrincipal {
+				t.Errorf("principal = %v, want %v", tx.Principal, tt.wantPrincipal)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSubmitEarlyRedemption(t *testing.T) {
+	lock := regexp.QuoteMeta("select * from Orders where OrderID = ? for update")
+	apy := regexp.QuoteMeta("select DefaultAPY from StakingProducts where ID = ?")
+	latest := regexp.QuoteMeta("select ID, OrderID, Time, APY, InterestGain, TotalInterestGain from OrderInterest where OrderID = ? order by ID desc limit 1")
+	redeem := regexp.QuoteMeta("update Orders set Type = 'Complete', TotalInterestGained = AccumulatedInterest where OrderID = ? and Type = 'Holding'")
+	renewedMaturity := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	locked := func(mock sqlmock.Sqlmock) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(lock).WithArgs("7").WillReturnRows(sqlmock.NewRows(orderColumns).AddRow("7", "1", "did:metablox:alice", models.OrderTypeHolding, 30, 0, 0, "", 100, "0xaa", false, false, nil, renewedMaturity, nil))
+		mock.ExpectQuery(apy).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"DefaultAPY"}).AddRow(10))
+		mock.ExpectQuery(latest).WithArgs("7").WillReturnRows(sqlmock.NewRows([]string{"ID", "OrderID", "Time", "APY", "InterestGain", "TotalInterestGain"}).AddRow("3", "7", time.Now(), 10, 1, 1))
+	}
+
+	tests := []struct {
+		name     string
+		validate ValidateEarlyRedemption
+		expect   func(mock sqlmock.Sqlmock)
+		wantKind apperrors.Kind
+	}{
+		{
+			name:     "locked order rejected",
+			validate: func(order *models.Order) error { return apperrors.Forbidden("order has reached the end of its term") },
+			expect: func(mock sqlmock.Sqlmock) {
+				locked(mock)
+				mock.ExpectRollback()
+			},
+			wantKind: apperrors.KindForbidden,
+		},
+		{
+			name:     "order left holding after validation",
+			validate: func(order *models.Order) error { return nil },
+			expect: func(mock sqlmock.Sqlmock) {
+				locked(mock)
+				mock.ExpectExec(redeem).WithArgs("7").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			wantKind: apperrors.KindConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			tt.expect(mock)
+			tx := models.NewTXInfo()
+			tx.OrderID = "7"
+			tx.TXType = "EarlyRedeem"
+			accrue := func(basis *models.AccrualBasis) *models.OrderInterest { return nil }
+			var validated *models.Order
+			validate := func(order *models.Order) error {
+				validated = order
+				return tt.validate(order)
+			}
+
+			_, err := SubmitEarlyRedemption(context.Background(), models.NewActor("test", ""), tx, accrue, validate, 0.5)
+			if apperrors.KindOf(err) != tt.wantKind {
+				t.Fatalf("error = %v, want kind %v", err, tt.wantKind)
+			}
+			if validated == nil || !validated.MaturityDate.Time.Equal(renewedMaturity) {
+				t.Errorf("validated order = %+v, want the locked row", validated)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package dao
+
+import (
+	"strings"
+
+	"github.com/metabloxStaking/models"
+)
+
+// listQuery assembles the where clause of a paginated list query. Pages are keyed on the table's
+// auto-increment primary key so that every page is an index range scan no matter how deep the
+// client has paged.
+type listQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+func newListQuery(condition string, args ...interface{}) *listQuery {
+	return &listQuery{
+		conditions: []string{condition},
+		args:       args,
+	}
+}
+
+func (q *listQuery) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+func (q *listQuery) whereDateRange(column string, opts *models.ListOptions) {
+	if !opts.From.IsZero() {
+		q.where(column+" >= ?", opts.From)
+	}
+	if !opts.To.IsZero() {
+		q.where(column+" < ?", opts.To)
+	}
+}
+
+// build appends the cursor condition, sort order and limit. One row more than the page size is
+// requested so that the caller can tell whether there is a next page.
+func (q *listQuery) build(selectStr, keyColumn string, opts *models.ListOptions) (string, []interface{}) {
+	order := "desc"
+	comparison := " < ?"
+	if opts.Sort == models.SortAscending {
+		order = "asc"
+		comparison = " > ?"
+	}
+	if opts.Cursor != "" {
+		q.where(keyColumn+comparison, opts.Cursor)
+	}
+
+	sqlStr := selectStr + " where " + strings.Join(q.conditions, " and ") + " order by " + keyColumn + " " + order + " limit ?"
+	return sqlStr, append(q.args, opts.Limit+1)
+}
+
+// nextKey reports the key to continue from, or an empty string when the last page has been reached
+func nextKey(count int, opts *models.ListOptions, lastKey func(i int) string) string {
+	if count <= opts.Limit {
+		return ""
+	}
+	return lastKey(opts.Limit - 1)
+}
@@ -0,0 +1,239 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+)
+
+const stakingProductColumns = "ID, ProductName, MinOrderValue, TopUpLimit, MinRedeemValue, LockUpPeriod, DefaultAPY, CreateDate, StartDate, Term, BurnedInterest, State, Retired"
+
+func GetStakingProductByID(ctx context.Context, productID string) (*models.StakingProduct, error) {
+	ctx, done := queryContext(ctx, "GetStakingProductByID")
+	defer done()
+	product := models.NewStakingProduct()
+	sqlStr := "select " + stakingProductColumns + " from StakingProducts where ID = ?"
+	err := SqlDB.GetContext(ctx, product, sqlStr, productID)
+	if err != nil {
+		return nil, notFound(err, "product")
+	}
+	return product, nil
+}
+
+// GetActiveStakingProducts returns every product that has not yet been settled
+func GetActiveStakingProducts(ctx context.Context) ([]*models.StakingProduct, error) {
+	ctx, done := queryContext(ctx, "GetActiveStakingProducts")
+	defer done()
+	var products []*models.StakingProduct
+	sqlStr := "select " + stakingProductColumns + " from StakingProducts where State <> 'Settled'"
+	err := SqlDB.SelectContext(ctx, &products, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// CountLiveOrdersForProduct counts the orders that have not yet been completed
+func CountLiveOrdersForProduct(ctx context.Context, productID string) (int, error) {
+	ctx, done := queryContext(ctx, "CountLiveOrdersForProduct")
+	defer done()
+	var count int
+	sqlStr := "select count(*) from Orders where ProductID = ? and Type <> 'Complete'"
+	err := SqlDB.GetContext(ctx, &count, sqlStr, productID)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func CreateProduct(ctx context.Context, product *models.StakingProduct, operator string) (string, error) {
+	ctx, done := txContext(ctx, "CreateProduct")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	sqlStr := "insert into StakingProducts (ProductName, MinOrderValue, TopUpLimit, MinRedeemValue, LockUpPeriod, DefaultAPY, StartDate, Term, BurnedInterest, State, Retired) values (:ProductName, :MinOrderValue, :TopUpLimit, :MinRedeemValue, :LockUpPeriod, :DefaultAPY, :StartDate, :Term, 0, :State, 0)"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, product)
+	if err != nil {
+		dbTX.Rollback()
+		return "", err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		dbTX.Rollback()
+		return "", err
+	}
+	product.ID = strconv.FormatInt(id, 10)
+
+	err = insertProductHistory(ctx, dbTX, product.ID, models.ProductActionCreate, nil, product, operator)
+	if err != nil {
+		dbTX.Rollback()
+		return "", err
+	}
+	return product.ID, dbTX.Commit()
+}
+
+// ValidateProductUpdate checks an update against the product as it stands once locked, given the
+// number of orders that are not yet complete, how many of those are still pending, and the
+// principal they hold
+type ValidateProductUpdate func(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error
+
+// UpdateProduct replaces the configurable fields of a product that has not been retired. The
+// product is locked while validate runs, so that no order can be bought into it in between.
+func UpdateProduct(ctx context.Context, product *models.StakingProduct, validate ValidateProductUpdate, operator string) error {
+	ctx, done := txContext(ctx, "UpdateProduct")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	old, err := lockProduct(ctx, dbTX, product.ID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	if old.Retired || old.State == models.ProductStateSettled {
+		dbTX.Rollback()
+		return apperrors.Conflict("product has been retired and can no longer be changed")
+	}
+	var liveOrders, pendingOrders int
+	sqlStr := "select count(*), coalesce(sum(Type = 'Pending'), 0) from Orders where ProductID = ? and Type in ('Pending', 'Holding', 'Matured')"
+	err = dbTX.QueryRowxContext(ctx, sqlStr, product.ID).Scan(&liveOrders, &pendingOrders)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	var totalPrincipal float64
+	sqlStr = "select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'"
+	err = dbTX.GetContext(ctx, &totalPrincipal, sqlStr, product.ID)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = validate(old, product, liveOrders, pendingOrders, totalPrincipal)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+
+	sqlStr = "update StakingProducts set ProductName = :ProductName, MinOrderValue = :MinOrderValue, TopUpLimit = :TopUpLimit, MinRedeemValue = :MinRedeemValue, LockUpPeriod = :LockUpPeriod, DefaultAPY = :DefaultAPY, StartDate = :StartDate, Term = :Term where ID = :ID and Retired = 0"
+	result, err := dbTX.NamedExecContext(ctx, sqlStr, product)
+	err = expectOneRow(result, err, "product has been retired and can no longer be changed")
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+
+	err = insertProductHistory(ctx, dbTX, product.ID, models.ProductActionUpdate, old, product, operator)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+// SetProductState moves a product from old's state to product's state and retired flag. The update
+// only applies if the product is still in old's state, so concurrent transitions cannot overwrite
+// each other.
+func SetProductState(ctx context.Context, old, product *models.StakingProduct, action string, operator string) error {
+	ctx, done := txContext(ctx, "SetProductState")
+	defer done()
+	dbTX, err := SqlDB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	sqlStr := "update StakingProducts set State = ?, Retired = ? where ID = ? and State = ?"
+	result, err := dbTX.ExecContext(ctx, sqlStr, product.State, product.Retired, product.ID, old.State)
+	err = expectOneRow(result, err, "product state has changed, please retry")
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	err = insertProductHistory(ctx, dbTX, product.ID, action, old, product, operator)
+	if err != nil {
+		dbTX.Rollback()
+		return err
+	}
+	return dbTX.Commit()
+}
+
+func GetProductHistory(ctx context.Context, productID string) ([]*models.ProductHistory, error) {
+	ctx, done := queryContext(ctx, "GetProductHistory")
+	defer done()
+	var history []*models.ProductHistory
+	sqlStr := "select ID, ProductID, Action, OldValue, NewValue, Operator, CreateDate from StakingProductHistory where ProductID = ? order by ID"
+	err := SqlDB.SelectContext(ctx, &history, sqlStr, productID)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// lockProduct reads a product and locks it for the rest of the transaction
+func lockProduct(ctx context.Context, dbTX *sqlx.Tx, productID string) (*models.StakingProduct, error) {
+	product := models.NewStakingProduct()
+	sqlStr := "select " + stakingProductColumns + " from StakingProducts where ID = ? for update"
+	err := dbTX.GetContext(ctx, product, sqlStr, productID)
+	if err != nil {
+		return nil, notFound(err, "product")
+	}
+	return product, nil
+}
+
+// expectOneRow checks that an update changed exactly one row, reporting a Conflict otherwise
+func expectOneRow(result sql.Result, err error, conflictMsg string) error {
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return apperrors.Conflict(conflictMsg)
+	}
+	return nil
+}
+
+func insertProductHistory(ctx context.Context, dbTX *sqlx.Tx, productID, action string, old, new *models.StakingProduct, operator string) error {
+	entry := models.NewProductHistory()
+	entry.ProductID = productID
+	entry.Action = action
+	entry.Operator = operator
+
+	var err error
+	entry.OldValue, err = marshalSnapshot(old)
+	if err != nil {
+		return err
+	}
+	entry.NewValue, err = marshalSnapshot(new)
+	if err != nil {
+		return err
+	}
+
+	sqlStr := "insert into StakingProductHistory (ProductID, Action, OldValue, NewValue, Operator) values (:ProductID, :Action, :OldValue, :NewValue, :Operator)"
+	_, err = dbTX.NamedExecContext(ctx, sqlStr, entry)
+	return err
+}
+
+func marshalSnapshot(product *models.StakingProduct) (*string, error) {
+	if product == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(product)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := string(data)
+	return &snapshot, nil
+}
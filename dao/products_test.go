@@ -0,0 +1,113 @@
+package dao
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+)
+
+func productRow(state string, retired bool) *sqlmock.Rows {
+	columns := []string{"ID", "ProductName", "MinOrderValue", "TopUpLimit", "MinRedeemValue", "LockUpPeriod", "DefaultAPY", "CreateDate", "StartDate", "Term", "BurnedInterest", "State", "Retired"}
+	return sqlmock.NewRows(columns).AddRow("1", "MBLX 180", 10, 1000, 1, 30, 0.1, nil, nil, 180, 0, state, retired)
+}
+
+func TestUpdateProduct(t *testing.T) {
+	lock := regexp.QuoteMeta("select " + stakingProductColumns + " from StakingProducts where ID = ? for update")
+	live := regexp.QuoteMeta("select count(*), coalesce(sum(Type = 'Pending'), 0) from Orders where ProductID = ? and Type in ('Pending', 'Holding', 'Matured')")
+	principal := regexp.QuoteMeta("select coalesce(sum(Amount), 0) from Orders where ProductID = ? and Type = 'Holding'")
+	update := regexp.QuoteMeta("update StakingProducts set")
+	history := regexp.QuoteMeta("insert into StakingProductHistory")
+
+	tests := []struct {
+		name     string
+		expect   func(mock sqlmock.Sqlmock)
+		validate ValidateProductUpdate
+		wantKind apperrors.Kind
+		wantErr  bool
+	}{
+		{
+			name: "validated against the locked product",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("1").WillReturnRows(productRow(models.ProductStateOpen, false))
+				mock.ExpectQuery(live).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"live", "pending"}).AddRow(3, 1))
+				mock.ExpectQuery(principal).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(250))
+				mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(history).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			validate: func(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error {
+				if old.DefaultAPY != 0.1 || liveOrders != 3 || pendingOrders != 1 || totalPrincipal != 250 {
+					t.Errorf("validate(APY %v, %d orders, %d pending, principal %v), want (0.1, 3, 1, 250)", old.DefaultAPY, liveOrders, pendingOrders, totalPrincipal)
+				}
+				return nil
+			},
+		},
+		{
+			name: "rejected update is rolled back",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("1").WillReturnRows(productRow(models.ProductStateOpen, false))
+				mock.ExpectQuery(live).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"live", "pending"}).AddRow(1, 0))
+				mock.ExpectQuery(principal).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(0))
+				mock.ExpectRollback()
+			},
+			validate: func(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error {
+				return apperrors.Conflict("rejected")
+			},
+			wantErr:  true,
+			wantKind: apperrors.KindConflict,
+		},
+		{
+			name: "retired product",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("1").WillReturnRows(productRow(models.ProductStateClosed, true))
+				mock.ExpectRollback()
+			},
+			validate: func(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error {
+				t.Error("validate called for a retired product")
+				return nil
+			},
+			wantErr:  true,
+			wantKind: apperrors.KindConflict,
+		},
+		{
+			name: "missing product",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(lock).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"ID"}))
+				mock.ExpectRollback()
+			},
+			validate: func(old, updated *models.StakingProduct, liveOrders, pendingOrders int, totalPrincipal float64) error {
+				t.Error("validate called for a missing product")
+				return nil
+			},
+			wantErr:  true,
+			wantKind: apperrors.KindNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := mockDB(t)
+			tt.expect(mock)
+			product := models.NewStakingProduct()
+			product.ID = "1"
+			err := UpdateProduct(context.Background(), product, tt.validate, "admin")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && apperrors.KindOf(err) != tt.wantKind {
+				t.Errorf("error kind = %v, want %v", apperrors.KindOf(err), tt.wantKind)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"github.com/metabloxStaking/models"
+)
+
+// GetTransferTransactions returns the transactions that should have moved tokens on chain,
+// recorded within the given time range
+func GetTransferTransactions(ctx context.Context, from, to time.Time) ([]*models.TXInfo, error) {
+	ctx, done := queryContext(ctx, "GetTransferTransactions")
+	defer done()
+	var transactions []*models.TXInfo
+	sqlStr := "select PaymentNo, OrderID, TXCurrencyType, TXType, TXHash, Principal, Interest, UserAddress, CreateDate, RedeemableTime from TXInfo where TXType in ('BuyIn', 'TopUp', 'Redeem', 'Harvest', 'EarlyRedeem') and CreateDate >= ? and CreateDate <= ? order by PaymentNo"
+	err := SqlDB.SelectContext(ctx, &transactions, sqlStr, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// OpenReconciliationTicket records a finding for follow-up. A finding that already has a ticket
+// is skipped, so the command can be rerun over overlapping ranges.
+func OpenReconciliationTicket(ctx context.Context, ticket *models.ReconciliationTicket) (bool, error) {
+	ctx, done := queryContext(ctx, "OpenReconciliationTicket")
+	defer done()
+	sqlStr := "insert into ReconciliationTickets (Kind, TXHash, PaymentNo, Detail, Status) values (:Kind, :TXHash, :PaymentNo, :Detail, 'Open')"
+	_, err := SqlDB.NamedExecContext(ctx, sqlStr, ticket)
+	if isDuplicateEntry(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/models"
+)
+
+// GetOutstandingLiabilities returns what the treasury owes: the principal of every order that has
+// not been redeemed plus its unharvested interest
+func GetOutstandingLiabilities(ctx context.Context) (float64, error) {
+	ctx, done := queryContext(ctx, "GetOutstandingLiabilities")
+	defer done()
+	var total float64
+	sqlStr := "select coalesce(sum(Amount + AccumulatedInterest - TotalInterestGained), 0) from Orders where Type in ('Holding', 'Matured')"
+	err := SqlDB.GetContext(ctx, &total, sqlStr)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// QueuePayout holds back a payout until the treasury can cover it. An order can only have one
+// queued payout at a time.
+func QueuePayout(ctx context.Context, payout *models.PayoutRequest) (string, error) {
+	ctx, done := queryContext(ctx, "QueuePayout")
+	defer done()
+	var queued int
+	sqlStr := "select count(*) from PayoutQueue where OrderID = ? and Status = 'Queued'"
+	err := SqlDB.GetContext(ctx, &queued, sqlStr, payout.OrderID)
+	if err != nil {
+		return "", err
+	}
+	if queued > 0 {
+		return "", apperrors.Conflict("a payout for this order is already queued")
+	}
+
+	payout.Status = models.PayoutStatusQueued
+	payout.CreateDate = models.NewTimestamp(clock.Now())
+	payout.UpdateDate = payout.CreateDate
+	sqlStr = "insert into PayoutQueue (OrderID, PayoutType, Amount, Status, Actor, RequestID, Detail, CreateDate, UpdateDate) values (:OrderID, :PayoutType, :Amount, :Status, :Actor, :RequestID, :Detail, :CreateDate, :UpdateDate)"
+	result, err := SqlDB.NamedExecContext(ctx, sqlStr, payout)
+	if err != nil {
+		return "", err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), nil
+}
+
+// GetPayouts lists payouts in the order they were queued, optionally only those with the given status
+func GetPayouts(ctx context.Context, status string) ([]*models.PayoutRequest, error) {
+	ctx, done := queryContext(ctx, "GetPayouts")
+	defer done()
+	var payouts []*models.PayoutRequest
+	sqlStr := "select ID, OrderID, PayoutType, Amount, Status, Actor, RequestID, Detail, CreateDate, UpdateDate from PayoutQueue"
+	var args []interface{}
+	if status != "" {
+		sqlStr += " where Status = ?"
+		args = append(args, status)
+	}
+	err := SqlDB.SelectContext(ctx, &payouts, sqlStr+" order by ID", args...)
+	if err != nil {
+		return nil, err
+	}
+	return payouts, nil
+}
+
+// UpdatePayoutStatus closes out a queued payout
+func UpdatePayoutStatus(ctx context.Context, id, status, detail string) error {
+	ctx, done := queryContext(ctx, "UpdatePayoutStatus")
+	defer done()
+	sqlStr := "update PayoutQueue set Status = ?, Detail = ?, UpdateDate = ? where ID = ? and Status = 'Queued'"
+	result, err := SqlDB.ExecContext(ctx, sqlStr, status, detail, models.NewTimestamp(clock.Now()), id)
+	return expectOneRow(result, err, "payout is no longer queued")
+}
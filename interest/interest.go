@@ -1,4 +1,80 @@
 package interest
 
-func CalculateInterest() { //placeholder, should query Colin's code to update interest values in db
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+const daysPerYear = 365
+
+// CalculateInterest brings a Holding order's accrued interest up to date. Interest accrues on the
+// order principal at the product APY for every whole day since the last accrual, or since the
+// buy-in if nothing has accrued yet.
+func CalculateInterest(ctx context.Context, actor *models.Actor, orderID string) error {
+	return dao.AccrueOrderInterest(ctx, actor, orderID, Until(clock.Now(), false))
+}
+
+// Until returns the accrual of a Holding order's interest on its current principal up to the given
+// time. Unless partialDays is set, only whole days count. Recording a partial-day accrual
+// re-baselines the order so that accrual on a changed principal starts from that time.
+func Until(until time.Time, partialDays bool) dao.Accrue {
+	return accrual(settings.Current(), until, partialDays)
+}
+
+// Preview returns the accrual that Until would record for the order, without recording it
+func Preview(ctx context.Context, orderID string, until time.Time, partialDays bool) (*models.OrderInterest, error) {
+	basis, err := dao.GetAccrualBasis(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return Until(until, partialDays)(basis), nil
+}
+
+func accrual(runtime *settings.Runtime, until time.Time, partialDays bool) dao.Accrue {
+	return func(basis *models.AccrualBasis) *models.OrderInterest {
+		if basis.Order.Type != models.OrderTypeHolding {
+			return nil
+		}
+		return accrue(basis, runtime.APY(basis.Order.ProductID, basis.DefaultAPY), until, partialDays)
+	}
+}
+
+func accrue(basis *models.AccrualBasis, apy float64, until time.Time, partialDays bool) *models.OrderInterest {
+	baseline := basis.BuyinDate
+	var unharvested float64
+	if basis.Latest != nil {
+		baseline = basis.Latest.Time
+		unharvested = basis.Latest.TotalInterestGain
+	}
+
+	//accruals are stored to the second
+	until = until.Truncate(time.Second)
+	if !until.After(baseline.Time) {
+		return nil
+	}
+	days := until.Sub(baseline.Time).Hours() / 24
+	end := until
+	if !partialDays {
+		days = math.Floor(days)
+		if days < 1 {
+			return nil
+		}
+		end = baseline.AddDate(0, 0, int(days))
+	}
+
+	gain := basis.Order.Amount * apy / 100 * days / daysPerYear
+
+	accrual := models.NewOrderInterest()
+	accrual.OrderID = basis.Order.OrderID
+	accrual.Time = models.NewTimestamp(end)
+	accrual.APY = apy
+	accrual.InterestGain = gain
+	accrual.TotalInterestGain = unharvested + gain
+	return accrual
 }
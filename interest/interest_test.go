@@ -0,0 +1,151 @@
+package interest
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+func TestAccrue(t *testing.T) {
+	buyin := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	order := &models.Order{OrderID: "7", ProductID: "1", Type: models.OrderTypeHolding, Amount: 3650}
+	latest := &models.OrderInterest{OrderID: "7", Time: models.NewTimestamp(buyin.AddDate(0, 0, 10)), TotalInterestGain: 5}
+
+	tests := []struct {
+		name        string
+		latest      *models.OrderInterest
+		until       time.Time
+		partialDays bool
+		wantNil     bool
+		wantTime    time.Time
+		wantGain    float64
+		wantTotal   float64
+	}{
+		{
+			name:      "whole days since the buy-in",
+			until:     buyin.AddDate(0, 0, 3),
+			wantTime:  buyin.AddDate(0, 0, 3),
+			wantGain:  3,
+			wantTotal: 3,
+		},
+		{
+			name:      "part of a day is left for the next accrual",
+			until:     buyin.AddDate(0, 0, 3).Add(20 * time.Hour),
+			wantTime:  buyin.AddDate(0, 0, 3),
+			wantGain:  3,
+			wantTotal: 3,
+		},
+		{
+			name:    "less than a day",
+			until:   buyin.Add(23 * time.Hour),
+			wantNil: true,
+		},
+		{
+			name:        "partial days",
+			until:       buyin.Add(36 * time.Hour),
+			partialDays: true,
+			wantTime:    buyin.Add(36 * time.Hour),
+			wantGain:    1.5,
+			wantTotal:   1.5,
+		},
+		{
+			name:        "partial days are stored to the second",
+			until:       buyin.Add(12*time.Hour + 500*time.Millisecond),
+			partialDays: true,
+			wantTime:    buyin.Add(12 * time.Hour),
+			wantGain:    0.5,
+			wantTotal:   0.5,
+		},
+		{
+			name:        "nothing to accrue at the baseline",
+			until:       buyin.Add(900 * time.Millisecond),
+			partialDays: true,
+			wantNil:     true,
+		},
+		{
+			name:        "baseline in the future",
+			until:       buyin.Add(-time.Hour),
+			partialDays: true,
+			wantNil:     true,
+		},
+		{
+			name:      "continues from the latest accrual",
+			latest:    latest,
+			until:     latest.Time.AddDate(0, 0, 2),
+			wantTime:  latest.Time.AddDate(0, 0, 2),
+			wantGain:  2,
+			wantTotal: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			basis := &models.AccrualBasis{Order: order, DefaultAPY: 10, Latest: tt.latest, BuyinDate: models.NewTimestamp(buyin)}
+			got := accrue(basis, 10, tt.until, tt.partialDays)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("accrue() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("accrue() = nil")
+			}
+			if !got.Time.Equal(tt.wantTime) {
+				t.Errorf("Time = %v, want %v", got.Time, tt.wantTime)
+			}
+			if math.Abs(got.InterestGain-tt.wantGain) > 1e-9 {
+				t.Errorf("InterestGain = %v, want %v", got.InterestGain, tt.wantGain)
+			}
+			if math.Abs(got.TotalInterestGain-tt.wantTotal) > 1e-9 {
+				t.Errorf("TotalInterestGain = %v, want %v", got.TotalInterestGain, tt.wantTotal)
+			}
+			if got.OrderID != order.OrderID || got.APY != 10 {
+				t.Errorf("OrderID, APY = %s, %v, want %s, 10", got.OrderID, got.APY, order.OrderID)
+			}
+		})
+	}
+}
+
+func TestAccrualAPY(t *testing.T) {
+	buyin := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	until := buyin.AddDate(0, 0, 1)
+	runtime := &settings.Runtime{APYOverrides: map[string]float64{"2": 36.5}}
+
+	tests := []struct {
+		name      string
+		productID string
+		orderType string
+		wantAPY   float64
+		wantGain  float64
+		wantNil   bool
+	}{
+		{"product default", "1", models.OrderTypeHolding, 3.65, 0.1, false},
+		{"configured override", "2", models.OrderTypeHolding, 36.5, 1, false},
+		{"order not holding", "1", models.OrderTypeMatured, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{OrderID: "7", ProductID: tt.productID, Type: tt.orderType, Amount: 1000}
+			basis := &models.AccrualBasis{Order: order, DefaultAPY: 3.65, BuyinDate: models.NewTimestamp(buyin)}
+			got := accrual(runtime, until, false)(basis)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("accrual = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("accrual = nil")
+			}
+			if got.APY != tt.wantAPY {
+				t.Errorf("APY = %v, want %v", got.APY, tt.wantAPY)
+			}
+			if math.Abs(got.InterestGain-tt.wantGain) > 1e-9 {
+				t.Errorf("InterestGain = %v, want %v", got.InterestGain, tt.wantGain)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/interest"
+	"github.com/metabloxStaking/models"
+)
+
+const accrualActor = "system:accrual"
+
+// AccrueInterest brings every Holding order's interest up to date, so that balances and the
+// ledger do not depend on the order being read
+func AccrueInterest(ctx context.Context) error {
+	actor := models.NewActor(accrualActor, "")
+	orderIDs, err := dao.GetHoldingOrderIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, orderID := range orderIDs {
+		err = interest.CalculateInterest(ctx, actor, orderID)
+		if err != nil {
+			//keep going so that one bad order does not hold up the rest
+			logger.Error("failed to accrue interest for order " + orderID + ": " + err.Error())
+		}
+	}
+	return nil
+}
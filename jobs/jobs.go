@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/metrics"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/products"
+	"github.com/metabloxStaking/tracing"
+)
+
+const defaultRolloverInterval = time.Hour
+const defaultAccrualInterval = time.Hour
+const defaultProductStateInterval = time.Minute
+const defaultLedgerCheckInterval = time.Hour
+const defaultSolvencyInterval = 5 * time.Minute
+const defaultClockSyncInterval = 10 * time.Second
+const defaultBusinessMetricsInterval = time.Minute
+
+var (
+	stop    = make(chan struct{})
+	running sync.WaitGroup
+)
+
+// Start launches the background jobs. Each job runs once immediately and then on its interval.
+func Start() {
+	launch("rollover", configuredInterval("jobs.rolloverInterval", defaultRolloverInterval), RolloverMaturedOrders)
+	launch("accrual", configuredInterval("jobs.accrualInterval", defaultAccrualInterval), AccrueInterest)
+	launch("product_state", configuredInterval("jobs.productStateInterval", defaultProductStateInterval), products.RefreshAll)
+	launch("ledger_check", configuredInterval("jobs.ledgerCheckInterval", defaultLedgerCheckInterval), CheckLedger)
+	launch("solvency", configuredInterval("jobs.solvencyInterval", defaultSolvencyInterval), CheckSolvency)
+	launch("business_metrics", configuredInterval("jobs.businessMetricsInterval", defaultBusinessMetricsInterval), metrics.RefreshBusiness)
+	if _, ok := clock.Fake(); ok {
+		launch("clock_sync", configuredInterval("jobs.clockSyncInterval", defaultClockSyncInterval), dao.SyncFakeClock)
+	}
+}
+
+type heartbeat struct {
+	interval time.Duration
+	lastRun  time.Time
+}
+
+var (
+	heartbeatMu sync.RWMutex
+	heartbeats  = make(map[string]*heartbeat)
+)
+
+// Heartbeats reports when each job last started a run. A job is considered stalled once it has
+// gone more than two intervals without starting.
+func Heartbeats() []*models.JobHeartbeat {
+	heartbeatMu.RLock()
+	defer heartbeatMu.RUnlock()
+
+	var result []*models.JobHeartbeat
+	for name, beat := range heartbeats {
+		status := models.NewJobHeartbeat(name)
+		status.Interval = beat.interval.String()
+		status.LastRun = models.NewTimestamp(beat.lastRun)
+		status.Healthy = time.Since(beat.lastRun) <= 2*beat.interval
+		result = append(result, status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func beat(name string, interval time.Duration) {
+	heartbeatMu.Lock()
+	defer heartbeatMu.Unlock()
+	heartbeats[name] = &heartbeat{interval: interval, lastRun: time.Now()}
+}
+
+// Stop tells the jobs to stop and waits for any run in progress to finish
+func Stop() {
+	close(stop)
+	running.Wait()
+}
+
+func launch(name string, interval time.Duration, job func(ctx context.Context) error) {
+	running.Add(1)
+	go run(name, interval, job)
+}
+
+func run(name string, interval time.Duration, job func(ctx context.Context) error) {
+	defer running.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		beat(name, interval)
+		start := time.Now()
+		//a run is not cancelled on shutdown; Stop waits for it, and each operation has its own timeout
+		ctx, span := tracing.Start(context.Background(), "job."+name)
+		err := job(ctx)
+		tracing.End(span, err)
+		metrics.ObserveJob(name, time.Since(start), err)
+		if err != nil {
+			logger.Error(name + " job failed: " + err.Error())
+		}
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func configuredInterval(key string, fallback time.Duration) time.Duration {
+	interval := viper.GetDuration(key)
+	if interval <= 0 {
+		return fallback
+	}
+	return interval
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/dao"
+)
+
+// CheckLedger verifies the ledger invariants and reports any violation so that it is caught
+// between reconciliations rather than when a payout goes wrong
+func CheckLedger(ctx context.Context) error {
+	check, err := dao.CheckLedger(ctx)
+	if err != nil {
+		return err
+	}
+	if !check.Balanced {
+		logger.WithFields(logger.Fields{
+			"total":              check.Total,
+			"unbalancedJournals": check.UnbalancedJournals,
+			"mismatchedOrders":   check.MismatchedOrders,
+		}).Error("ledger check failed")
+		return fmt.Errorf("ledger is out of balance by %f", check.Total)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/interest"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/terms"
+)
+
+const rolloverActor = "system:rollover"
+
+// RolloverMaturedOrders handles Holding orders whose closure window has passed without a
+// redemption. Orders that opted into auto-renew start a new term on the same product, optionally
+// compounding their unharvested interest, as long as the product is Open and has room for the new
+// principal; all others move to Matured and can be redeemed at any time.
+func RolloverMaturedOrders(ctx context.Context) error {
+	now := clock.Now()
+	actor := models.NewActor(rolloverActor, "")
+	orders, err := dao.GetOrdersClosedBefore(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if order.AutoRenew {
+			err = renewOrder(ctx, actor, order, now)
+		} else {
+			err = matureOrder(ctx, actor, order)
+		}
+		if err != nil {
+			//keep going so that one bad order does not hold up the rest
+			logger.Error("failed to roll over order " + order.OrderID + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func renewOrder(ctx context.Context, actor *models.Actor, order *models.Order, now time.Time) error {
+	if order.ClosureWindowEnd.IsZero() {
+		return errors.New("order has no term dates")
+	}
+	product, err := dao.GetProductInfoByID(ctx, order.ProductID)
+	if err != nil {
+		return err
+	}
+	totalPrincipal, err := dao.GetProductTotalPrincipal(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+	reason := renewalRefusal(order, product, totalPrincipal)
+	if reason != "" {
+		logger.Info("maturing order " + order.OrderID + " instead of renewing it: " + reason)
+		return matureOrder(ctx, actor, order)
+	}
+	//the new term picks up from the end of the previous one, using the product's current term
+	dates, err := terms.Compute(order.ClosureWindowEnd.Time, product.Term, product.LockUpPeriod)
+	if err != nil {
+		return err
+	}
+	dates.Apply(order, product.Term)
+
+	txInfo := models.NewTXInfo()
+	txInfo.OrderID = order.OrderID
+	txInfo.TXCurrencyType = "MBLX"
+	txInfo.TXType = "Renew"
+	txInfo.UserAddress = order.UserAddress
+	txInfo.RedeemableTime = order.MaturityDate
+
+	return dao.RenewOrder(ctx, actor, txInfo, order, interest.Until(now, true), order.CompoundInterest)
+}
+
+// renewalRefusal returns why the order cannot be renewed into its product, or "" if it can.
+// totalPrincipal is the Holding principal staked in the product, including this order's.
+func renewalRefusal(order *models.Order, product *models.ProductDetails, totalPrincipal float64) string {
+	if product.State != models.ProductStateOpen {
+		return "product " + product.ID + " is " + product.State
+	}
+	if order.CompoundInterest && totalPrincipal+order.AccumulatedInterest-order.TotalInterestGained > product.TopUpLimit {
+		return "compounding its interest would take product " + product.ID + " over its principal limit"
+	}
+	return ""
+}
+
+func matureOrder(ctx context.Context, actor *models.Actor, order *models.Order) error {
+	err := interest.CalculateInterest(ctx, actor, order.OrderID)
+	if err != nil {
+		return err
+	}
+	return dao.MatureOrder(ctx, actor, order.OrderID)
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/metabloxStaking/models"
+)
+
+func TestRenewalRefusal(t *testing.T) {
+	tests := []struct {
+		name           string
+		state          string
+		compound       bool
+		totalPrincipal float64
+		wantRefused    bool
+	}{
+		{"open product", models.ProductStateOpen, false, 1000, false},
+		{"compounding within the limit", models.ProductStateOpen, true, 990, false},
+		{"compounding over the limit", models.ProductStateOpen, true, 995, true},
+		{"not compounding at the limit", models.ProductStateOpen, false, 1000, false},
+		{"sold out product", models.ProductStateSoldOut, false, 1000, true},
+		{"closed product", models.ProductStateClosed, false, 100, true},
+		{"settled product", models.ProductStateSettled, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.NewOrder()
+			order.OrderID = "7"
+			order.Amount = 100
+			order.AccumulatedInterest = 15
+			order.TotalInterestGained = 5
+			order.CompoundInterest = tt.compound
+			product := models.NewProductDetails()
+			product.ID = "1"
+			product.State = tt.state
+			product.TopUpLimit = 1000
+
+			reason := renewalRefusal(order, product, tt.totalPrincipal)
+			if (reason != "") != tt.wantRefused {
+				t.Errorf("renewalRefusal() = %q, want refused %v", reason, tt.wantRefused)
+			}
+		})
+	}
+}
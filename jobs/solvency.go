@@ -0,0 +1,18 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/metabloxStaking/payouts"
+	"github.com/metabloxStaking/treasury"
+)
+
+// CheckSolvency refreshes the treasury coverage and, once the treasury is covered, pays out
+// anything that was queued while it was not
+func CheckSolvency(ctx context.Context) error {
+	err := treasury.Check(ctx)
+	if err != nil {
+		return err
+	}
+	return payouts.ProcessQueue(ctx)
+}
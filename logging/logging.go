@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"context"
+	"os"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/metabloxStaking/settings"
+)
+
+type requestIDKey struct{}
+
+// Init configures the global logger from the log section of the config. Logs are JSON unless
+// log.format is "text".
+func Init() error {
+	logger.SetOutput(os.Stdout)
+	if viper.GetString("log.format") == "text" {
+		logger.SetFormatter(&logger.TextFormatter{FullTimestamp: true})
+	} else {
+		logger.SetFormatter(&logger.JSONFormatter{})
+	}
+	//the level is part of the runtime settings and follows changes to the config
+	logger.SetLevel(settings.Current().LogLevel)
+	return nil
+}
+
+// WithRequestID returns a context carrying the ID of the request being served
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string outside a request
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns a log entry tagged with the request ID and trace carried by ctx, if any
+func FromContext(ctx context.Context) *logger.Entry {
+	entry := logger.NewEntry(logger.StandardLogger())
+	if requestID := RequestID(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		entry = entry.WithFields(logger.Fields{
+			"trace_id": spanContext.TraceID().String(),
+			"span_id":  spanContext.SpanID().String(),
+		})
+	}
+	return entry
+}
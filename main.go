@@ -1,25 +1,65 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"os/signal"
+	"syscall"
 
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/controllers"
 	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/jobs"
+	"github.com/metabloxStaking/logging"
+	"github.com/metabloxStaking/metrics"
 	"github.com/metabloxStaking/routers"
+	"github.com/metabloxStaking/server"
 	"github.com/metabloxStaking/settings"
+	"github.com/metabloxStaking/tracing"
+	"github.com/spf13/viper"
 )
 
 func main() {
-	err := settings.Init()
+	configPath := flag.String("config", "", "path to the config file; defaults to $METABLOX_CONFIG or ./config.yaml")
+	flag.Parse()
+
+	err := settings.Init(*configPath)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = logging.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	shutdownTracing, err := tracing.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = controllers.RegisterValidators()
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 
-	/*err = contract.Init()
+	err = clock.Init()
 	if err != nil {
 		fmt.Println(err)
 		return
-	}*/
+	}
+
+	err = contract.Init()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
 
 	err = dao.InitSql()
 	if err != nil {
@@ -27,5 +67,65 @@ func main() {
 		return
 	}
 
-	routers.Setup()
+	if viper.GetBool("mysql.migrate") {
+		err = dao.Migrate(context.Background())
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+
+	err = dao.SyncFakeClock(context.Background())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	err = metrics.RegisterDBStats()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	router, err := routers.Setup()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	jobs.Start()
+
+	metricsDone := make(chan struct{})
+	go func() {
+		defer close(metricsDone)
+		//metrics are not worth taking the API down for, so a listener that fails is only reported
+		err := server.RunInternal(ctx, server.NewMetrics())
+		if err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	err = server.Run(ctx, server.New(router))
+	if err != nil {
+		fmt.Println(err)
+	}
+	stop()
+	<-metricsDone
+
+	//shut down in dependency order: nothing new arrives once the server has drained, and the
+	//jobs finish their current run before the connections they use are closed
+	jobs.Stop()
+	err = dao.CloseSql()
+	if err != nil {
+		fmt.Println(err)
+	}
+	contract.Close()
+	//flush the spans of the last requests and job runs
+	err = shutdownTracing(context.Background())
+	if err != nil {
+		fmt.Println(err)
+	}
 }
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+// RegisterDBStats exposes the connection pool statistics of dao.SqlDB. Call it once the
+// database has been opened.
+func RegisterDBStats() error {
+	return prometheus.Register(collectors.NewDBStatsCollector(dao.SqlDB.DB, "metablox"))
+}
+
+// businessCollector reports the staking figures last read by RefreshBusiness. They are read from
+// the database on the refresh job's interval rather than on every scrape, so that scrapes put no
+// load on the database.
+type businessCollector struct {
+	tvl              *prometheus.Desc
+	orders           *prometheus.Desc
+	payoutQueueDepth *prometheus.Desc
+	accrualLag       *prometheus.Desc
+}
+
+// businessFigures holds the latest staking figures; a figure that failed to refresh is left out
+// rather than reported stale
+type businessFigures struct {
+	tvl              map[string]float64
+	orders           map[string]int
+	payoutQueueDepth *int
+	oldestAccrual    *models.Timestamp
+}
+
+var (
+	businessMu sync.RWMutex
+	business   = &businessFigures{}
+)
+
+func newBusinessCollector() *businessCollector {
+	return &businessCollector{
+		tvl: prometheus.NewDesc(namespace+"_product_tvl", "Principal staked in holding and matured orders, by product.",
+			[]string{"product"}, nil),
+		orders: prometheus.NewDesc(namespace+"_orders", "Number of orders, by status.",
+			[]string{"status"}, nil),
+		payoutQueueDepth: prometheus.NewDesc(namespace+"_payout_queue_depth", "Payouts waiting for the treasury to be covered.",
+			nil, nil),
+		accrualLag: prometheus.NewDesc(namespace+"_accrual_lag_seconds", "Time since the least recently accrued holding order last accrued interest.",
+			nil, nil),
+	}
+}
+
+func (b *businessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.tvl
+	ch <- b.orders
+	ch <- b.payoutQueueDepth
+	ch <- b.accrualLag
+}
+
+func (b *businessCollector) Collect(ch chan<- prometheus.Metric) {
+	businessMu.RLock()
+	figures := business
+	businessMu.RUnlock()
+
+	for product, value := range figures.tvl {
+		ch <- prometheus.MustNewConstMetric(b.tvl, prometheus.GaugeValue, value, product)
+	}
+	for status, count := range figures.orders {
+		ch <- prometheus.MustNewConstMetric(b.orders, prometheus.GaugeValue, float64(count), status)
+	}
+	if figures.payoutQueueDepth != nil {
+		ch <- prometheus.MustNewConstMetric(b.payoutQueueDepth, prometheus.GaugeValue, float64(*figures.payoutQueueDepth))
+	}
+	if figures.oldestAccrual != nil {
+		lag := 0.0
+		if !figures.oldestAccrual.IsZero() {
+			lag = clock.Since(figures.oldestAccrual.Time).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(b.accrualLag, prometheus.GaugeValue, lag)
+	}
+}
+
+// RefreshBusiness reads the staking figures reported by /metrics from the database. It returns
+// the first error, after refreshing every figure it can.
+func RefreshBusiness(ctx context.Context) error {
+	figures := &businessFigures{}
+	var firstErr error
+	keep := func(err error) bool {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return err == nil
+	}
+
+	tvl, err := dao.GetProductTVL(ctx)
+	if keep(err) {
+		figures.tvl = tvl
+	}
+	orders, err := dao.CountOrdersByStatus(ctx)
+	if keep(err) {
+		figures.orders = orders
+	}
+	depth, err := dao.CountQueuedPayouts(ctx)
+	if keep(err) {
+		figures.payoutQueueDepth = &depth
+	}
+	oldest, err := dao.GetOldestAccrual(ctx)
+	if keep(err) {
+		figures.oldestAccrual = &oldest
+	}
+
+	businessMu.Lock()
+	business = figures
+	businessMu.Unlock()
+	return firstErr
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/metabloxStaking/dao"
+)
+
+func TestRefreshBusiness(t *testing.T) {
+	tvl := regexp.QuoteMeta("select ProductID, coalesce(sum(Amount), 0) as Total from Orders")
+	orders := regexp.QuoteMeta("select Type, count(*) as Count from Orders group by Type")
+	queue := regexp.QuoteMeta("select count(*) from PayoutQueue where Status = 'Queued'")
+	oldest := regexp.QuoteMeta("select min(coalesce(")
+
+	tests := []struct {
+		name        string
+		queueErr    error
+		wantErr     bool
+		wantMetrics int
+	}{
+		{"every figure refreshed", nil, false, 6},
+		{"failed figure is left out", errors.New("connection refused"), true, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			mock.ExpectQuery(tvl).WillReturnRows(sqlmock.NewRows([]string{"ProductID", "Total"}).AddRow("1", 100).AddRow("2", 50))
+			mock.ExpectQuery(orders).WillReturnRows(sqlmock.NewRows([]string{"Type", "Count"}).AddRow("Holding", 2).AddRow("Pending", 1))
+			if tt.queueErr != nil {
+				mock.ExpectQuery(queue).WillReturnError(tt.queueErr)
+			} else {
+				mock.ExpectQuery(queue).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+			}
+			mock.ExpectQuery(oldest).WillReturnRows(sqlmock.NewRows([]string{"oldest"}).AddRow(time.Now().Add(-time.Hour)))
+
+			err = RefreshBusiness(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			//scrapes report the refreshed figures without querying the database again
+			got := testutil.CollectAndCount(newBusinessCollector())
+			if got != tt.wantMetrics {
+				t.Errorf("collected %d metrics, want %d", got, tt.wantMetrics)
+			}
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "metablox"
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	httpRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_request_errors_total",
+		Help:      "HTTP requests answered with a 4xx or 5xx status, by route.",
+	}, []string{"method", "route", "status"})
+
+	chainRPCDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "chain_rpc_duration_seconds",
+		Help:      "Time taken by calls to the chain node, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	chainRPCFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chain_rpc_failures_total",
+		Help:      "Failed calls to the chain node, by method.",
+	}, []string{"method"})
+
+	jobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Time taken by each run of a background job.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 300, 900},
+	}, []string{"job"})
+
+	jobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_failures_total",
+		Help:      "Background job runs that returned an error.",
+	}, []string{"job"})
+
+	jobLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of a background job.",
+	}, []string{"job"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		httpRequestDuration,
+		httpRequestErrors,
+		chainRPCDuration,
+		chainRPCFailures,
+		jobDuration,
+		jobFailures,
+		jobLastSuccess,
+		newBusinessCollector(),
+	)
+}
+
+func ObserveHTTPRequest(method, route, status string, code int, elapsed time.Duration) {
+	httpRequestDuration.WithLabelValues(method, route, status).Observe(elapsed.Seconds())
+	if code >= 400 {
+		httpRequestErrors.WithLabelValues(method, route, status).Inc()
+	}
+}
+
+// ObserveChainCall times a call to the chain node and counts it as failed if it returns an error
+func ObserveChainCall(method string, call func() error) error {
+	start := time.Now()
+	err := call()
+	chainRPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		chainRPCFailures.WithLabelValues(method).Inc()
+	}
+	return err
+}
+
+func ObserveJob(job string, elapsed time.Duration, err error) {
+	jobDuration.WithLabelValues(job).Observe(elapsed.Seconds())
+	if err != nil {
+		jobFailures.WithLabelValues(job).Inc()
+		return
+	}
+	jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+}
@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/settings"
+)
+
+const IdempotencyHeader = "Idempotency-Key"
+const IdempotencyReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+const defaultIdempotencyLockTimeout = 60
+
+// maxIdempotentBodyBytes bounds the request body read into memory for the fingerprint
+const maxIdempotentBodyBytes = 1 << 20
+
+// bodyRecorder keeps a copy of everything the handler writes so it can be stored for replays
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a route safe to retry. Requests carrying an Idempotency-Key header are
+// fingerprinted; the first successful response is stored and replayed for every retry with the
+// same key, and a key reused with a different request is rejected. Keys are scoped to the caller,
+// so operator routes must authenticate before this runs.
+func Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			controllers.ResponseErr(c, apperrors.Validation("Idempotency-Key is too long"))
+			c.Abort()
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodyBytes))
+		if err != nil {
+			controllers.ResponseErrorWithStatus(c, http.StatusRequestEntityTooLarge, controllers.CodeInvalidParams)
+			c.Abort()
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		fingerprint := fingerprintRequest(c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, body)
+		scope, err := idempotencyScope(c, body)
+		if err != nil {
+			controllers.ResponseErr(c, err)
+			c.Abort()
+			return
+		}
+
+		reserved, err := reserveKey(c.Request.Context(), scope, key, fingerprint)
+		if err != nil {
+			controllers.ResponseErr(c, err)
+			c.Abort()
+			return
+		}
+		if !reserved {
+			replayResponse(c, scope, key, fingerprint)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = recorder
+		c.Next()
+
+		//the response is stored even if the client has gone away, so that its retry is replayed
+		ctx := context.Background()
+		status := recorder.Status()
+		if status >= http.StatusOK && status < http.StatusMultipleChoices {
+			err = dao.SaveIdempotencyResponse(ctx, scope, key, status, recorder.body.Bytes())
+		} else {
+			//failed requests are not stored so that the client can retry once the problem is resolved
+			err = dao.DeleteIdempotencyKey(ctx, scope, key)
+		}
+		if err != nil {
+			logger.Error("failed to update idempotency key " + key + ": " + err.Error())
+		}
+	}
+}
+
+// idempotencyScope identifies the caller a key belongs to: the operator on admin routes, otherwise
+// the DID the request is made for, taken from the body or from the owner of the order it names.
+// Requests that name neither share the empty scope.
+func idempotencyScope(c *gin.Context, body []byte) (string, error) {
+	if operator := c.GetString(controllers.OperatorContextKey); operator != "" {
+		return "operator:" + operator, nil
+	}
+	var fields struct {
+		UserDID string
+		OrderID string
+	}
+	//a body that does not parse is rejected by the handler; it only lacks a DID here
+	json.Unmarshal(body, &fields)
+	if fields.UserDID != "" {
+		return fields.UserDID, nil
+	}
+	orderID := fields.OrderID
+	if orderID == "" {
+		orderID = c.Param("id")
+	}
+	if orderID == "" {
+		return "", nil
+	}
+	order, err := dao.GetOrderByID(c.Request.Context(), orderID)
+	if err != nil {
+		return "", err
+	}
+	return order.UserDID, nil
+}
+
+func reserveKey(ctx context.Context, scope, key, fingerprint string) (bool, error) {
+	reserved, err := dao.ReserveIdempotencyKey(ctx, scope, key, fingerprint)
+	if err != nil || reserved {
+		return reserved, err
+	}
+
+	lockTimeout := settings.Current().IdempotencyLockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = defaultIdempotencyLockTimeout
+	}
+	released, err := dao.ReleaseStaleIdempotencyKey(ctx, scope, key, lockTimeout)
+	if err != nil || !released {
+		return false, err
+	}
+	return dao.ReserveIdempotencyKey(ctx, scope, key, fingerprint)
+}
+
+func replayResponse(c *gin.Context, scope, key, fingerprint string) {
+	ctx := c.Request.Context()
+	record, err := dao.GetIdempotencyRecord(ctx, scope, key)
+	if err != nil {
+		controllers.ResponseErr(c, err)
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		controllers.ResponseErrorWithStatus(c, http.StatusUnprocessableEntity, controllers.CodeIdempotencyKeyReused)
+		return
+	}
+	if record.StatusCode == 0 {
+		controllers.ResponseErrorWithStatus(c, http.StatusConflict, controllers.CodeRequestInProgress)
+		return
+	}
+	c.Header(IdempotencyReplayedHeader, "true")
+	c.Data(record.StatusCode, gin.MIMEJSON, record.ResponseBody)
+}
+
+func fingerprintRequest(method, path, rawQuery string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write([]byte(rawQuery))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
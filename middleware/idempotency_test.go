@@ -0,0 +1,291 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/dao"
+)
+
+func TestFingerprintRequest(t *testing.T) {
+	base := fingerprintRequest("POST", "/order/create", "", []byte(`{"Amount":1}`))
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		rawQuery string
+		body     string
+		same     bool
+	}{
+		{"identical request", "POST", "/order/create", "", `{"Amount":1}`, true},
+		{"different body", "POST", "/order/create", "", `{"Amount":2}`, false},
+		{"different path", "POST", "/order/confirm", "", `{"Amount":1}`, false},
+		{"different method", "PUT", "/order/create", "", `{"Amount":1}`, false},
+		{"different query", "POST", "/order/create", "epoch_millis=true", `{"Amount":1}`, false},
+		{"path and body run together", "POST", "/order/create{", "", `"Amount":1}`, false},
+		{"query and body run together", "POST", "/order/create", "{", `"Amount":1}`, false},
+		{"empty body", "POST", "/order/create", "", ``, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fingerprintRequest(tt.method, tt.path, tt.rawQuery, []byte(tt.body))
+			if (got == base) != tt.same {
+				t.Errorf("fingerprint equal = %v, want %v", got == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestIdempotency(t *testing.T) {
+	const key = "key-1"
+	const body = `{"UserDID":"did:metablox:alice"}`
+	const scope = "did:metablox:alice"
+	fingerprint := fingerprintRequest(http.MethodPost, "/order", "", []byte(body))
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+
+	reserve := regexp.QuoteMeta("insert into IdempotencyKeys")
+	release := regexp.QuoteMeta("delete from IdempotencyKeys where Scope = ? and IdempotencyKey = ? and StatusCode = 0")
+	record := regexp.QuoteMeta("select IdempotencyKey, Fingerprint, StatusCode, ResponseBody, CreateDate from IdempotencyKeys")
+	save := regexp.QuoteMeta("update IdempotencyKeys set StatusCode = ?, ResponseBody = ?")
+	remove := regexp.QuoteMeta("delete from IdempotencyKeys where Scope = ? and IdempotencyKey = ?")
+	recordColumns := []string{"IdempotencyKey", "Fingerprint", "StatusCode", "ResponseBody", "CreateDate"}
+
+	tests := []struct {
+		name          string
+		key           string
+		body          string
+		handlerStatus int
+		expect        func(mock sqlmock.Sqlmock)
+		wantStatus    int
+		wantCalled    bool
+		wantReplayed  bool
+		wantBody      string
+	}{
+		{
+			name:          "no key",
+			handlerStatus: http.StatusOK,
+			expect:        func(mock sqlmock.Sqlmock) {},
+			wantStatus:    http.StatusOK,
+			wantCalled:    true,
+		},
+		{
+			name:       "key too long",
+			key:        strings.Repeat("k", maxIdempotencyKeyLength+1),
+			expect:     func(mock sqlmock.Sqlmock) {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			key:        key,
+			body:       `{"UserDID":"` + strings.Repeat("a", maxIdempotentBodyBytes) + `"}`,
+			expect:     func(mock sqlmock.Sqlmock) {},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "first request stores its response",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WithArgs(scope, key, fingerprint).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(save).WithArgs(http.StatusOK, sqlmock.AnyArg(), scope, key).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+		{
+			name:          "failed request releases the key",
+			key:           key,
+			handlerStatus: http.StatusConflict,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WithArgs(scope, key, fingerprint).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(remove).WithArgs(scope, key).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusConflict,
+			wantCalled: true,
+		},
+		{
+			name: "retry replays the stored response",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WillReturnError(duplicate)
+				mock.ExpectExec(release).WithArgs(scope, key, defaultIdempotencyLockTimeout).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(record).WithArgs(scope, key).WillReturnRows(sqlmock.NewRows(recordColumns).
+					AddRow(key, fingerprint, http.StatusOK, []byte(`{"stored":true}`), nil))
+			},
+			wantStatus:   http.StatusOK,
+			wantReplayed: true,
+			wantBody:     `{"stored":true}`,
+		},
+		{
+			name: "key reused with a different request",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WillReturnError(duplicate)
+				mock.ExpectExec(release).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(record).WithArgs(scope, key).WillReturnRows(sqlmock.NewRows(recordColumns).
+					AddRow(key, "other", http.StatusOK, []byte(`{}`), nil))
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "first request still in progress",
+			key:  key,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WillReturnError(duplicate)
+				mock.ExpectExec(release).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectQuery(record).WithArgs(scope, key).WillReturnRows(sqlmock.NewRows(recordColumns).
+					AddRow(key, fingerprint, 0, nil, nil))
+			},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:          "stale reservation is taken over",
+			key:           key,
+			handlerStatus: http.StatusOK,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(reserve).WillReturnError(duplicate)
+				mock.ExpectExec(release).WithArgs(scope, key, defaultIdempotencyLockTimeout).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(reserve).WithArgs(scope, key, fingerprint).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(save).WithArgs(http.StatusOK, sqlmock.AnyArg(), scope, key).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			tt.expect(mock)
+
+			called := false
+			r := gin.New()
+			r.POST("/order", Idempotency(), func(c *gin.Context) {
+				called = true
+				c.JSON(tt.handlerStatus, &controllers.ResponseData{})
+			})
+
+			requestBody := body
+			if tt.body != "" {
+				requestBody = tt.body
+			}
+			req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(requestBody))
+			if tt.key != "" {
+				req.Header.Set(IdempotencyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %v, want %v", called, tt.wantCalled)
+			}
+			if replayed := w.Header().Get(IdempotencyReplayedHeader) == "true"; replayed != tt.wantReplayed {
+				t.Errorf("replayed = %v, want %v", replayed, tt.wantReplayed)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %s, want %s", w.Body.String(), tt.wantBody)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	lookup := regexp.QuoteMeta("select * from Orders where OrderID = ?")
+
+	tests := []struct {
+		name      string
+		operator  string
+		path      string
+		body      string
+		expect    func(mock sqlmock.Sqlmock)
+		wantScope string
+		wantErr   bool
+	}{
+		{name: "operator", operator: "alice", path: "/products/3", body: `{"UserDID":"did:metablox:bob"}`, wantScope: "operator:alice"},
+		{name: "DID in the body", path: "/order/create", body: `{"UserDID":"did:metablox:bob","Amount":1}`, wantScope: "did:metablox:bob"},
+		{
+			name: "owner of the order in the body",
+			path: "/order/confirm",
+			body: `{"OrderID":"7"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lookup).WithArgs("7").WillReturnRows(sqlmock.NewRows([]string{"OrderID", "UserDID"}).AddRow("7", "did:metablox:carol"))
+			},
+			wantScope: "did:metablox:carol",
+		},
+		{
+			name: "owner of the order in the path",
+			path: "/orders/8",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lookup).WithArgs("8").WillReturnRows(sqlmock.NewRows([]string{"OrderID", "UserDID"}).AddRow("8", "did:metablox:dave"))
+			},
+			wantScope: "did:metablox:dave",
+		},
+		{
+			name: "missing order",
+			path: "/orders/9",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(lookup).WithArgs("9").WillReturnRows(sqlmock.NewRows([]string{"OrderID", "UserDID"}))
+			},
+			wantErr: true,
+		},
+		{name: "nothing identifies the caller", path: "/order/create", body: `not json`, wantScope: ""},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			if tt.expect != nil {
+				tt.expect(mock)
+			}
+
+			var scope string
+			r := gin.New()
+			handler := func(c *gin.Context) {
+				if tt.operator != "" {
+					c.Set(controllers.OperatorContextKey, tt.operator)
+				}
+				scope, err = idempotencyScope(c, []byte(tt.body))
+			}
+			r.POST("/orders/:id", handler)
+			r.POST("/products/:id", handler)
+			r.POST("/order/:action", handler)
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, tt.path, nil))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if scope != tt.wantScope {
+				t.Errorf("scope = %q, want %q", scope, tt.wantScope)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/logging"
+)
+
+// AccessLog writes one structured line per request once it has been handled
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		fields := logger.Fields{
+			"method":     c.Request.Method,
+			"route":      c.FullPath(),
+			"path":       c.Request.URL.Path,
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip":  c.ClientIP(),
+		}
+		if did := requestDID(c); did != "" {
+			fields["did"] = did
+		}
+		if orderID := c.GetString(controllers.LogOrderIDKey); orderID != "" {
+			fields["order_id"] = orderID
+		}
+		if operator := c.GetString(controllers.OperatorContextKey); operator != "" {
+			fields["operator"] = operator
+		}
+
+		entry := logging.FromContext(c.Request.Context()).WithFields(fields)
+		switch {
+		case c.Writer.Status() >= http.StatusInternalServerError:
+			entry.Error("request")
+		case c.Writer.Status() >= http.StatusBadRequest:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+	}
+}
+
+func requestDID(c *gin.Context) string {
+	if did := c.GetString(controllers.LogDIDKey); did != "" {
+		return did
+	}
+	return c.Param("did")
+}
+
+// Recovery turns a panic in a handler into a 500 response in the usual format and logs the
+// stack trace, instead of dropping the connection
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			logging.FromContext(c.Request.Context()).WithFields(logger.Fields{
+				"method": c.Request.Method,
+				"path":   c.Request.URL.Path,
+				"panic":  recovered,
+				"stack":  string(debug.Stack()),
+			}).Error("recovered from panic")
+
+			if !c.Writer.Written() {
+				controllers.ResponseErrorWithStatus(c, http.StatusInternalServerError, controllers.CodeInternalError)
+			}
+			c.Abort()
+		}()
+		c.Next()
+	}
+}
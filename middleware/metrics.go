@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/metrics"
+)
+
+const unmatchedRoute = "unmatched"
+
+// Metrics records the latency and status of every request against its route pattern, so that
+// paths with IDs do not create a series per ID
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		code := c.Writer.Status()
+		metrics.ObserveHTTPRequest(c.Request.Method, route, strconv.Itoa(code), code, time.Since(start))
+	}
+}
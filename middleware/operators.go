@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/logging"
+	"github.com/metabloxStaking/settings"
+)
+
+const OperatorKeyHeader = "X-API-Key"
+
+const RoleViewer = "viewer"
+const RoleSupport = "support"
+const RoleTreasurer = "treasurer"
+const RoleAdmin = "admin"
+
+var roles = map[string]bool{
+	RoleViewer:    true,
+	RoleSupport:   true,
+	RoleTreasurer: true,
+	RoleAdmin:     true,
+}
+
+// OperatorAuth authenticates operators listed under operators in the config, each with an apiKey
+// and a role. The operator's name and role are stored in the context, and every request made
+// through the group is logged with the operator's identity.
+func OperatorAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(OperatorKeyHeader)
+		if key == "" {
+			controllers.ResponseErrorWithStatus(c, http.StatusUnauthorized, controllers.CodeNeedLogin)
+			c.Abort()
+			return
+		}
+
+		name, operator := lookupOperator(key)
+		if operator == nil {
+			controllers.ResponseErrorWithStatus(c, http.StatusUnauthorized, controllers.CodeInvalidAuth)
+			c.Abort()
+			return
+		}
+		c.Set(controllers.OperatorContextKey, name)
+		c.Set(controllers.OperatorRoleContextKey, operator.Role)
+
+		c.Next()
+
+		logging.FromContext(c.Request.Context()).WithFields(logger.Fields{
+			"operator": name,
+			"role":     operator.Role,
+			"method":   c.Request.Method,
+			"path":     c.Request.URL.Path,
+			"status":   c.Writer.Status(),
+		}).Info("operator request")
+	}
+}
+
+// RequireRole only lets through operators holding one of the given roles. Admins may use every
+// operator route.
+func RequireRole(allowed ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(controllers.OperatorRoleContextKey)
+		if role == RoleAdmin {
+			c.Next()
+			return
+		}
+		for _, r := range allowed {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		controllers.ResponseErrorWithStatus(c, http.StatusForbidden, controllers.CodeForbidden)
+		c.Abort()
+	}
+}
+
+func lookupOperator(key string) (string, *settings.Operator) {
+	var foundName string
+	var found *settings.Operator
+	for name, operator := range settings.Current().Operators {
+		//compare against every key so the response time does not reveal which keys exist
+		if operator == nil || operator.APIKey == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(operator.APIKey), []byte(key)) == 1 && roles[operator.Role] {
+			foundName = name
+			found = operator
+		}
+	}
+	return foundName, found
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/settings"
+)
+
+// idleLimiterTimeout is how long a client's limiter is kept after its last request
+const idleLimiterTimeout = 10 * time.Minute
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit allows each client IP the request rate in the runtime settings. The rate follows
+// config changes without a restart; a rate of zero lets every request through. At most
+// rateLimit.maxClients clients are tracked at once, so that a flood of addresses cannot exhaust
+// memory; clients beyond that share one limiter until idle ones are swept.
+func RateLimit() gin.HandlerFunc {
+	var mu sync.Mutex
+	clients := make(map[string]*clientLimiter)
+	overflow := &clientLimiter{}
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		limit := settings.Current().RateLimit
+		if limit.RequestsPerSecond <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		ip := c.ClientIP()
+		mu.Lock()
+		if now.Sub(lastSweep) > idleLimiterTimeout {
+			for ip, client := range clients {
+				if now.Sub(client.lastSeen) > idleLimiterTimeout {
+					delete(clients, ip)
+				}
+			}
+			lastSweep = now
+		}
+		client, ok := clients[ip]
+		if !ok && len(clients) < limit.MaxClients {
+			client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)}
+			clients[ip] = client
+		} else if !ok {
+			if overflow.limiter == nil {
+				overflow.limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), limit.Burst)
+			}
+			client = overflow
+		}
+		client.lastSeen = now
+		client.limiter.SetLimitAt(now, rate.Limit(limit.RequestsPerSecond))
+		client.limiter.SetBurstAt(now, limit.Burst)
+		allowed := client.limiter.AllowN(now, 1)
+		mu.Unlock()
+
+		if !allowed {
+			controllers.ResponseErrorWithStatus(c, http.StatusTooManyRequests, controllers.CodeTooManyRequests)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
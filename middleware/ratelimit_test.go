@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/metabloxStaking/settings"
+)
+
+func TestRateLimitMaxClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	settings.Set(&settings.Runtime{RateLimit: settings.RateLimit{RequestsPerSecond: 0.001, Burst: 1, MaxClients: 2}})
+	defer settings.Set(&settings.Runtime{})
+	r := gin.New()
+	r.Use(RateLimit())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	//requests are made in order against the same limiter
+	tests := []struct {
+		name       string
+		ip         string
+		wantStatus int
+	}{
+		{"first tracked client", "192.0.2.1", http.StatusOK},
+		{"second tracked client", "192.0.2.2", http.StatusOK},
+		{"first tracked client again", "192.0.2.1", http.StatusTooManyRequests},
+		{"first client over the cap", "192.0.2.3", http.StatusOK},
+		{"second client over the cap shares its limiter", "192.0.2.4", http.StatusTooManyRequests},
+		{"second tracked client again", "192.0.2.2", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.ip + ":1234"
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+-- The tables as they were before schema migrations were introduced. They are only created if
+-- missing, so existing databases are left as they are.
+
+create table if not exists StakingProducts (
+  ID int not null auto_increment,
+  ProductName varchar(255) not null,
+  MinOrderValue int not null default 0,
+  TopUpLimit double not null default 0,
+  MinRedeemValue int not null default 0,
+  LockUpPeriod int not null default 0,
+  DefaultAPY double not null default 0,
+  CreateDate datetime not null default current_timestamp,
+  StartDate datetime null,
+  Term int not null default 0,
+  BurnedInterest double not null default 0,
+  Status tinyint(1) not null default 0,
+  primary key (ID)
+) engine = InnoDB;
+
+create table if not exists Orders (
+  OrderID int not null auto_increment,
+  ProductID int not null,
+  UserDID varchar(255) not null,
+  Type varchar(16) not null,
+  Term int null,
+  AccumulatedInterest double not null default 0,
+  TotalInterestGained double not null default 0,
+  PaymentAddress varchar(255) not null,
+  Amount double not null,
+  UserAddress varchar(42) not null,
+  primary key (OrderID),
+  key Orders_ProductID (ProductID)
+) engine = InnoDB;
+
+create table if not exists TXInfo (
+  PaymentNo int not null auto_increment,
+  OrderID int not null,
+  TXCurrencyType varchar(16) not null,
+  TXType varchar(16) not null,
+  TXHash varchar(66) null,
+  Principal double not null default 0,
+  Interest double not null default 0,
+  UserAddress varchar(42) not null,
+  CreateDate datetime not null default current_timestamp,
+  RedeemableTime datetime null,
+  primary key (PaymentNo),
+  key TXInfo_OrderID (OrderID),
+  key TXInfo_TXHash (TXHash)
+) engine = InnoDB;
+
+create table if not exists OrderInterest (
+  ID int not null auto_increment,
+  OrderID int not null,
+  Time datetime not null,
+  APY double not null,
+  InterestGain double not null,
+  TotalInterestGain double not null,
+  primary key (ID),
+  key OrderInterest_OrderID (OrderID)
+) engine = InnoDB;
@@ -0,0 +1,11 @@
+-- Idempotency-Key reservations and the responses replayed for retries. StatusCode stays 0 while
+-- the first request is still in progress.
+
+create table IdempotencyKeys (
+  IdempotencyKey varchar(255) not null,
+  Fingerprint char(64) not null,
+  StatusCode int not null default 0,
+  ResponseBody mediumblob null,
+  CreateDate datetime not null default current_timestamp,
+  primary key (IdempotencyKey)
+) engine = InnoDB;
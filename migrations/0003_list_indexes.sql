@@ -0,0 +1,10 @@
+-- Indexes for the paginated list endpoints. Pages are keyed on each table's primary key, and an
+-- InnoDB secondary index ends with the primary key, so an index on the equality filter also serves
+-- the keyset range and sort order. Transactions and interest by order use the OrderID indexes of
+-- TXInfo and OrderInterest.
+
+-- staking records and transactions by user DID
+create index Orders_UserDID on Orders (UserDID);
+
+-- the buy-in joined to each staking record and the top-ups summed for it
+create index TXInfo_OrderID_TXType on TXInfo (OrderID, TXType);
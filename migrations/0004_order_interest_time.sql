@@ -0,0 +1,8 @@
+-- An order accrues at most once up to any given time. Accruals are recorded under the order's row
+-- lock, and this key catches any that still race. OrderInterest_OrderID stays for the interest
+-- listing, which is keyed on ID.
+--
+-- The statement fails if earlier, unlocked accruals left duplicates behind. Find them with
+--   select OrderID, Time, count(*) from OrderInterest group by OrderID, Time having count(*) > 1;
+-- and settle the double-counted interest on the order before running it again.
+alter table OrderInterest add unique key OrderInterest_OrderID_Time (OrderID, Time);
@@ -0,0 +1,5 @@
+-- Renewal preferences set by the user on each order. Existing orders mature at the end of their
+-- term without renewing.
+alter table Orders
+  add column AutoRenew tinyint(1) not null default 0,
+  add column CompoundInterest tinyint(1) not null default 0;
@@ -0,0 +1,18 @@
+-- Term boundaries stored on the order, so that renewal can move them. They stay null until the
+-- order is bought in.
+alter table Orders
+  add column LockUpEndDate datetime null,
+  add column MaturityDate datetime null,
+  add column ClosureWindowEnd datetime null;
+
+-- Orders bought in before this migration only carry their maturity, as the RedeemableTime of the
+-- buy-in transaction. The closure window is the day that follows it, and the lock-up runs from
+-- the day of the buy-in, capped at maturity.
+update Orders
+  join TXInfo on TXInfo.OrderID = Orders.OrderID and TXInfo.TXType = 'BuyIn'
+  join StakingProducts on StakingProducts.ID = Orders.ProductID
+set
+  Orders.MaturityDate = TXInfo.RedeemableTime,
+  Orders.ClosureWindowEnd = TXInfo.RedeemableTime + interval 1 day,
+  Orders.LockUpEndDate = least(date(TXInfo.CreateDate) + interval StakingProducts.LockUpPeriod day, TXInfo.RedeemableTime)
+where Orders.MaturityDate is null and TXInfo.RedeemableTime is not null;
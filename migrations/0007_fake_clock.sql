@@ -0,0 +1,8 @@
+-- The fake clock's offset, shared by every instance of a staging environment so that they all
+-- agree on the time. Holds a single row, created by the first instance to start with the fake clock.
+create table if not exists FakeClock (
+  ID tinyint not null,
+  OffsetNanos bigint not null,
+  UpdateDate datetime not null default current_timestamp on update current_timestamp,
+  primary key (ID)
+) engine = InnoDB;
@@ -0,0 +1,27 @@
+-- Every DATETIME column now holds UTC: the service connects with loc=UTC and time_zone '+00:00'.
+-- Rows written before that were in the server's time zone, both the ones written by the service and
+-- those filled in by column defaults. This converts them from the database's global time zone,
+-- which is a no-op where that is already UTC. The term dates backfilled by 0006 come from the same
+-- local values, so they are converted too.
+--
+-- This assumes the service and the database ran in the same time zone. If they did not, mark this
+-- migration applied before starting the service, with
+--   insert into SchemaMigrations (Version) values ('0008_utc_datetimes.sql');
+-- and convert the columns written by the service from its time zone by hand. A conversion that
+-- MySQL cannot make, such as from a named zone without the time zone tables loaded, leaves the row
+-- as it is.
+update StakingProducts set
+  CreateDate = coalesce(convert_tz(CreateDate, @@global.time_zone, '+00:00'), CreateDate),
+  StartDate = coalesce(convert_tz(StartDate, @@global.time_zone, '+00:00'), StartDate);
+
+update TXInfo set
+  CreateDate = coalesce(convert_tz(CreateDate, @@global.time_zone, '+00:00'), CreateDate),
+  RedeemableTime = coalesce(convert_tz(RedeemableTime, @@global.time_zone, '+00:00'), RedeemableTime);
+
+update OrderInterest set
+  Time = coalesce(convert_tz(Time, @@global.time_zone, '+00:00'), Time);
+
+update Orders set
+  LockUpEndDate = coalesce(convert_tz(LockUpEndDate, @@global.time_zone, '+00:00'), LockUpEndDate),
+  MaturityDate = coalesce(convert_tz(MaturityDate, @@global.time_zone, '+00:00'), MaturityDate),
+  ClosureWindowEnd = coalesce(convert_tz(ClosureWindowEnd, @@global.time_zone, '+00:00'), ClosureWindowEnd);
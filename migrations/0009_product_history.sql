@@ -0,0 +1,16 @@
+-- Products retired by an operator take no further changes, and every change made to a product is
+-- recorded with the operator who made it. OldValue and NewValue hold JSON snapshots of the product.
+alter table StakingProducts
+  add column Retired tinyint(1) not null default 0;
+
+create table StakingProductHistory (
+  ID int not null auto_increment,
+  ProductID int not null,
+  Action varchar(16) not null,
+  OldValue text null,
+  NewValue text null,
+  Operator varchar(255) not null,
+  CreateDate datetime not null default current_timestamp,
+  primary key (ID),
+  key StakingProductHistory_ProductID (ProductID)
+) engine = InnoDB;
@@ -0,0 +1,15 @@
+-- The product lifecycle state replaces the Status flag. Enabled products start out Upcoming or
+-- Open depending on their start date, and the scheduler moves any that are full to SoldOut on its
+-- first run. Disabled products are Closed, so an operator has to resume them.
+alter table StakingProducts
+  add column State varchar(16) not null default 'Upcoming';
+
+update StakingProducts
+set State = case
+  when Status = 0 then 'Closed'
+  when StartDate > utc_timestamp() then 'Upcoming'
+  else 'Open'
+end;
+
+alter table StakingProducts
+  drop column Status;
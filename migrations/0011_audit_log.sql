@@ -0,0 +1,36 @@
+-- Every change to an order, transaction or interest row. With audit.hashChain enabled, each row
+-- also stores a hash chained to the previous row's.
+create table AuditLog (
+  ID bigint not null auto_increment,
+  EntityType varchar(32) not null,
+  EntityID varchar(32) not null,
+  Action varchar(32) not null,
+  OldValue mediumtext null,
+  NewValue mediumtext null,
+  Actor varchar(255) not null,
+  RequestID varchar(64) not null default '',
+  PrevHash char(64) null,
+  Hash char(64) null,
+  CreateDate datetime not null default current_timestamp,
+  primary key (ID),
+  key AuditLog_Entity (EntityType, EntityID, ID),
+  key AuditLog_Actor (Actor, ID)
+) engine = InnoDB;
+
+-- The hash of the last chained row. Writers lock this single row, so the chain cannot fork even
+-- while the log is empty.
+create table AuditChainHead (
+  ID tinyint not null,
+  Hash char(64) null,
+  primary key (ID)
+) engine = InnoDB;
+
+insert into AuditChainHead (ID, Hash) values (1, null);
+
+-- The log is append-only. Creating triggers needs the TRIGGER privilege, and SUPER as well when
+-- binary logging is on unless log_bin_trust_function_creators is set.
+create trigger AuditLog_no_update before update on AuditLog for each row
+  signal sqlstate '45000' set message_text = 'AuditLog is append-only';
+
+create trigger AuditLog_no_delete before delete on AuditLog for each row
+  signal sqlstate '45000' set message_text = 'AuditLog is append-only';
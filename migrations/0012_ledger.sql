@@ -0,0 +1,50 @@
+-- The double-entry ledger. Each journal's entries sum to zero; Amount is positive for a debit and
+-- negative for a credit.
+create table LedgerJournals (
+  ID bigint not null auto_increment,
+  OrderID int not null,
+  Description varchar(32) not null,
+  CreateDate datetime not null default current_timestamp,
+  primary key (ID),
+  key LedgerJournals_OrderID (OrderID)
+) engine = InnoDB;
+
+create table LedgerEntries (
+  ID bigint not null auto_increment,
+  JournalID bigint not null,
+  Account varchar(32) not null,
+  OrderID int not null,
+  Amount double not null,
+  primary key (ID),
+  key LedgerEntries_JournalID (JournalID),
+  key LedgerEntries_OrderID_Account (OrderID, Account),
+  key LedgerEntries_Account (Account)
+) engine = InnoDB;
+
+-- Orders that were already holding before the ledger existed get an opening journal for the
+-- principal they hold and the interest accrued to them but not yet harvested. Completed orders
+-- have nothing left to carry over.
+insert into LedgerJournals (OrderID, Description, CreateDate)
+select OrderID, 'OpeningBalance', utc_timestamp()
+from Orders
+where Type in ('Holding', 'Matured');
+
+insert into LedgerEntries (JournalID, Account, OrderID, Amount)
+select LedgerJournals.ID, 'Treasury', Orders.OrderID, Orders.Amount
+from LedgerJournals join Orders on Orders.OrderID = LedgerJournals.OrderID
+where LedgerJournals.Description = 'OpeningBalance' and Orders.Amount <> 0;
+
+insert into LedgerEntries (JournalID, Account, OrderID, Amount)
+select LedgerJournals.ID, 'UserPrincipal', Orders.OrderID, -Orders.Amount
+from LedgerJournals join Orders on Orders.OrderID = LedgerJournals.OrderID
+where LedgerJournals.Description = 'OpeningBalance' and Orders.Amount <> 0;
+
+insert into LedgerEntries (JournalID, Account, OrderID, Amount)
+select LedgerJournals.ID, 'InterestExpense', Orders.OrderID, Orders.AccumulatedInterest - Orders.TotalInterestGained
+from LedgerJournals join Orders on Orders.OrderID = LedgerJournals.OrderID
+where LedgerJournals.Description = 'OpeningBalance' and Orders.AccumulatedInterest <> Orders.TotalInterestGained;
+
+insert into LedgerEntries (JournalID, Account, OrderID, Amount)
+select LedgerJournals.ID, 'UserInterestPayable', Orders.OrderID, Orders.TotalInterestGained - Orders.AccumulatedInterest
+from LedgerJournals join Orders on Orders.OrderID = LedgerJournals.OrderID
+where LedgerJournals.Description = 'OpeningBalance' and Orders.AccumulatedInterest <> Orders.TotalInterestGained;
@@ -0,0 +1,17 @@
+-- Findings from chain-to-database reconciliation that need following up. A finding is identified
+-- by its kind, transaction hash and payment number, either of which may be empty, so rerunning
+-- over an overlapping range opens no duplicate tickets.
+create table ReconciliationTickets (
+  ID int not null auto_increment,
+  Kind varchar(32) not null,
+  TXHash varchar(66) not null default '',
+  PaymentNo varchar(32) not null default '',
+  Detail varchar(1024) not null default '',
+  Status varchar(16) not null,
+  CreateDate datetime not null default current_timestamp,
+  primary key (ID),
+  unique key ReconciliationTickets_Finding (Kind, TXHash, PaymentNo)
+) engine = InnoDB;
+
+-- transactions recorded within the reconciled time range
+create index TXInfo_CreateDate on TXInfo (CreateDate);
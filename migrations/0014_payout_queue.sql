@@ -0,0 +1,17 @@
+-- Payouts held back while the treasury was under-collateralized, paid out in the order they were
+-- queued once it is covered again.
+create table PayoutQueue (
+  ID int not null auto_increment,
+  OrderID int not null,
+  PayoutType varchar(16) not null,
+  Amount double not null,
+  Status varchar(16) not null,
+  Actor varchar(255) not null,
+  RequestID varchar(64) not null default '',
+  Detail varchar(1024) not null default '',
+  CreateDate datetime not null default current_timestamp,
+  UpdateDate datetime not null default current_timestamp,
+  primary key (ID),
+  key PayoutQueue_Status (Status, ID),
+  key PayoutQueue_OrderID_Status (OrderID, Status)
+) engine = InnoDB;
@@ -0,0 +1,5 @@
+-- Idempotency keys belong to the caller that sent them, a DID or an operator, so that one client's
+-- key can never collide with another's. Reservations made before this are kept under the empty scope.
+
+alter table IdempotencyKeys add column Scope varchar(255) not null default '' first;
+alter table IdempotencyKeys drop primary key, add primary key (Scope, IdempotencyKey);
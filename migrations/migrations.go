@@ -0,0 +1,13 @@
+// Package migrations holds the database schema as numbered SQL files. dao.Migrate applies the
+// files that have not been applied yet in file name order, so a released file is never edited;
+// every change to the schema is a new file.
+//
+// Statements in a file are separated by a semicolon at the end of a line, and lines starting with
+// -- are comments. MySQL commits DDL as it goes, so a file that fails part way has to be finished
+// by hand before it is applied again.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var Files embed.FS
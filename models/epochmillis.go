@@ -0,0 +1,112 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EpochMillisSuffix names the field that carries a timestamp field as epoch milliseconds
+const EpochMillisSuffix = "Millis"
+
+var timestampType = reflect.TypeOf(Timestamp{})
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// WithEpochMillis returns v ready for JSON encoding with every Timestamp field accompanied by a
+// field of the same name plus EpochMillisSuffix, holding the time as epoch milliseconds or null
+// if it is unset. The timestamps themselves are still rendered as RFC 3339.
+func WithEpochMillis(v interface{}) interface{} {
+	return withEpochMillis(reflect.ValueOf(v))
+}
+
+func withEpochMillis(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return withEpochMillis(v.Elem())
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = withEpochMillis(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		entries := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			entries[fmt.Sprint(iter.Key().Interface())] = withEpochMillis(iter.Value())
+		}
+		return entries
+	case reflect.Struct:
+		if v.Type() == timestampType || v.Type().Implements(marshalerType) {
+			return v.Interface()
+		}
+		fields := make(map[string]interface{})
+		addFields(fields, v)
+		return fields
+	}
+	return v.Interface()
+}
+
+// addFields adds the struct's fields under the names encoding/json would give them, flattening
+// embedded structs
+func addFields(fields map[string]interface{}, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		value := v.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && value.Kind() == reflect.Struct && tag == "" {
+			addFields(fields, value)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			name = parts[0]
+		}
+		if len(parts) > 1 && parts[1] == "omitempty" && isEmpty(value) {
+			continue
+		}
+
+		fields[name] = withEpochMillis(value)
+		if value.Type() == timestampType {
+			timestamp := value.Interface().(Timestamp)
+			if timestamp.IsZero() {
+				fields[name+EpochMillisSuffix] = nil
+			} else {
+				fields[name+EpochMillisSuffix] = timestamp.EpochMillis()
+			}
+		}
+	}
+}
+
+// isEmpty matches encoding/json's omitempty
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
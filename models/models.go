@@ -3,61 +3,126 @@ package models
 const OrderTypePending = "Pending"
 const OrderTypeHolding = "Holding"
 const OrderTypeComplete = "Complete"
+const OrderTypeMatured = "Matured"
+
+const ProductActionCreate = "Create"
+const ProductActionUpdate = "Update"
+const ProductActionPause = "Pause"
+const ProductActionResume = "Resume"
+const ProductActionRetire = "Retire"
+const ProductActionSchedule = "Schedule"
+
+const AuditEntityOrder = "Order"
+const AuditEntityTransaction = "TXInfo"
+const AuditEntityOrderInterest = "OrderInterest"
+
+// Ledger accounts. Treasury and interest expense are debit-normal; the user liabilities and
+// burned interest are credit-normal.
+const LedgerAccountTreasury = "Treasury"
+const LedgerAccountUserPrincipal = "UserPrincipal"
+const LedgerAccountInterestPayable = "UserInterestPayable"
+const LedgerAccountInterestExpense = "InterestExpense"
+const LedgerAccountBurnedInterest = "BurnedInterest"
+
+const FindingMissingOnChain = "MissingOnChain"
+const FindingMissingInDB = "MissingInDB"
+const FindingDuplicate = "Duplicate"
+const FindingAmountMismatch = "AmountMismatch"
+
+const PayoutTypeRedeem = "Redeem"
+const PayoutTypeHarvest = "Harvest"
+
+const PayoutStatusQueued = "Queued"
+const PayoutStatusPaid = "Paid"
+const PayoutStatusFailed = "Failed"
+
+const ProductStateUpcoming = "Upcoming"
+const ProductStateOpen = "Open"
+const ProductStateSoldOut = "SoldOut"
+const ProductStateClosed = "Closed"
+const ProductStateSettled = "Settled"
+
+const SortAscending = "asc"
+const SortDescending = "desc"
 
 type Order struct {
-	OrderID             string  `db:"OrderID"`
-	ProductID           string  `db:"ProductID"`
-	UserDID             string  `db:"UserDID"`
-	Type                string  `db:"Type"`
-	Term                *int    `db:"Term"`
-	AccumulatedInterest float64 `db:"AccumulatedInterest"`
-	TotalInterestGained float64 `db:"TotalInterestGained"`
-	PaymentAddress      string  `db:"PaymentAddress"`
-	Amount              float64 `db:"Amount"`
-	UserAddress         string  `db:"UserAddress"`
+	OrderID             string    `db:"OrderID"`
+	ProductID           string    `db:"ProductID"`
+	UserDID             string    `db:"UserDID"`
+	Type                string    `db:"Type"`
+	Term                *int      `db:"Term"`
+	AccumulatedInterest float64   `db:"AccumulatedInterest"`
+	TotalInterestGained float64   `db:"TotalInterestGained"`
+	PaymentAddress      string    `db:"PaymentAddress"`
+	Amount              float64   `db:"Amount"`
+	UserAddress         string    `db:"UserAddress"`
+	AutoRenew           bool      `db:"AutoRenew"`
+	CompoundInterest    bool      `db:"CompoundInterest"`
+	LockUpEndDate       Timestamp `db:"LockUpEndDate"`
+	MaturityDate        Timestamp `db:"MaturityDate"`
+	ClosureWindowEnd    Timestamp `db:"ClosureWindowEnd"`
 }
 
 type StakingProduct struct {
-	ID             string  `db:"ID"`
-	ProductName    string  `db:"ProductName"`
-	MinOrderValue  int     `db:"MinOrderValue"`
-	TopUpLimit     float64 `db:"TopUpLimit"`
-	MinRedeemValue int     `db:"MinRedeemValue"`
-	LockUpPeriod   int     `db:"LockUpPeriod"`
-	DefaultAPY     float64 `db:"DefaultAPY"`
-	CreateDate     string  `db:"CreateDate"`
-	StartDate      string  `db:"StartDate"`
-	Term           int     `db:"Term"`
-	BurnedInterest float64 `db:"BurnedInterest"`
-	Status         bool    `db:"Status"`
+	ID             string    `db:"ID"`
+	ProductName    string    `db:"ProductName"`
+	MinOrderValue  int       `db:"MinOrderValue"`
+	TopUpLimit     float64   `db:"TopUpLimit"`
+	MinRedeemValue int       `db:"MinRedeemValue"`
+	LockUpPeriod   int       `db:"LockUpPeriod"`
+	DefaultAPY     float64   `db:"DefaultAPY"`
+	CreateDate     Timestamp `db:"CreateDate"`
+	StartDate      Timestamp `db:"StartDate"`
+	Term           int       `db:"Term"`
+	BurnedInterest float64   `db:"BurnedInterest"`
+	State          string    `db:"State"`
+	Retired        bool      `db:"Retired"`
+}
+
+type ProductHistory struct {
+	ID         string    `db:"ID"`
+	ProductID  string    `db:"ProductID"`
+	Action     string    `db:"Action"`
+	OldValue   *string   `db:"OldValue"`
+	NewValue   *string   `db:"NewValue"`
+	Operator   string    `db:"Operator"`
+	CreateDate Timestamp `db:"CreateDate"`
 }
 
 type User struct {
-	DID        string `db:"DID"`
-	Currency   string `db:"Currency"`
-	CreateDate string `db:"CreateDate"`
+	DID        string    `db:"DID"`
+	Currency   string    `db:"Currency"`
+	CreateDate Timestamp `db:"CreateDate"`
 }
 
 type TXInfo struct {
-	PaymentNo      string  `db:"PaymentNo"`
-	OrderID        string  `db:"OrderID"`
-	TXCurrencyType string  `db:"TXCurrencyType"`
-	TXType         string  `db:"TXType"`
-	TXHash         *string `db:"TXHash"`
-	Principal      float64 `db:"Principal"`
-	Interest       float64 `db:"Interest"`
-	UserAddress    string  `db:"UserAddress"`
-	CreateDate     string  `db:"CreateDate"`
-	RedeemableTime string  `db:"RedeemableTime"`
+	PaymentNo      string    `db:"PaymentNo"`
+	OrderID        string    `db:"OrderID"`
+	TXCurrencyType string    `db:"TXCurrencyType"`
+	TXType         string    `db:"TXType"`
+	TXHash         *string   `db:"TXHash"`
+	Principal      float64   `db:"Principal"`
+	Interest       float64   `db:"Interest"`
+	UserAddress    string    `db:"UserAddress"`
+	CreateDate     Timestamp `db:"CreateDate"`
+	RedeemableTime Timestamp `db:"RedeemableTime"`
 }
 
 type OrderInterest struct {
-	ID                string  `db:"ID"`
-	OrderID           string  `db:"OrderID"`
-	Time              string  `db:"Time"`
-	APY               float64 `db:"APY"`
-	InterestGain      float64 `db:"InterestGain"`
-	TotalInterestGain float64 `db:"TotalInterestGain"`
+	ID                string    `db:"ID"`
+	OrderID           string    `db:"OrderID"`
+	Time              Timestamp `db:"Time"`
+	APY               float64   `db:"APY"`
+	InterestGain      float64   `db:"InterestGain"`
+	TotalInterestGain float64   `db:"TotalInterestGain"`
+}
+
+// AccrualBasis is what an order's next interest accrual is worked out from
+type AccrualBasis struct {
+	Order      *Order
+	DefaultAPY float64
+	Latest     *OrderInterest //nil until the order has accrued interest
+	BuyinDate  Timestamp      //only read while Latest is nil
 }
 
 type PaymentInfo struct {
@@ -68,23 +133,26 @@ type PaymentInfo struct {
 }
 
 type PrincipalUpdates struct {
-	ID             string  `db:"ID"`
-	ProductID      string  `db:"ProductID"`
-	Time           string  `db:"Time"`
-	TotalPrincipal float64 `db:"TotalPrincipal"`
+	ID             string    `db:"ID"`
+	ProductID      string    `db:"ProductID"`
+	Time           Timestamp `db:"Time"`
+	TotalPrincipal float64   `db:"TotalPrincipal"`
 }
 
 type StakingRecord struct {
-	OrderID           string  `db:"OrderID"`
-	ProductID         string  `db:"ProductID"`
-	OrderStatus       string  `db:"Type"`
-	Term              *int    `db:"Term"`
-	PurchaseTime      string  `db:"CreateDate"`
-	PrincipalAmount   float64 `db:"Amount"`
-	TXCurrencyType    string  `db:"TXCurrencyType"`
+	OrderID           string    `db:"OrderID"`
+	ProductID         string    `db:"ProductID"`
+	OrderStatus       string    `db:"Type"`
+	Term              *int      `db:"Term"`
+	PurchaseTime      Timestamp `db:"CreateDate"`
+	PrincipalAmount   float64   `db:"Amount"`
+	TXCurrencyType    string    `db:"TXCurrencyType"`
+	TopUpAmount       float64   `db:"TopUpAmount"`
 	InterestGain      float64
 	TotalAmount       float64
-	RedeemableTime    string `db:"RedeemableTime"`
+	LockUpEndTime     Timestamp `db:"LockUpEndDate"`
+	RedeemableTime    Timestamp `db:"RedeemableTime"`
+	ClosureWindowEnd  Timestamp `db:"ClosureWindowEnd"`
 	IsInClosureWindow bool
 }
 
@@ -95,8 +163,9 @@ type ProductDetails struct {
 	TopUpLimit     float64 `db:"TopUpLimit"`
 	MinRedeemValue int     `db:"MinRedeemValue"`
 	LockUpPeriod   int     `db:"LockUpPeriod"`
+	Term           int     `db:"Term"`
 	CurrentAPY     float64
-	Status         bool `db:"Status"`
+	State          string `db:"State"`
 }
 
 type OrderInterestInfo struct {
@@ -104,11 +173,218 @@ type OrderInterestInfo struct {
 	TotalInterestGained float64 `db:"TotalInterestGained"`
 }
 
+type IdempotencyRecord struct {
+	Key          string    `db:"IdempotencyKey"`
+	Fingerprint  string    `db:"Fingerprint"`
+	StatusCode   int       `db:"StatusCode"`
+	ResponseBody []byte    `db:"ResponseBody"`
+	CreateDate   Timestamp `db:"CreateDate"`
+}
+
+type ListOptions struct {
+	Limit     int
+	Cursor    string
+	Sort      string
+	Status    string
+	TXType    string
+	ProductID string
+	From      Timestamp
+	To        Timestamp
+	// Scope identifies the listing the options apply to, so that a cursor cannot be replayed
+	// against a different one
+	Scope string
+}
+
+type Page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor"`
+}
+
 type CreateOrderInput struct {
+	Amount           float64 `binding:"required,gt=0"`
+	UserAddress      string  `binding:"required,eth_addr"`
+	UserDID          string  `binding:"required,did"`
+	ProductID        string  `binding:"required,numeric_id"`
+	AutoRenew        bool
+	CompoundInterest bool
+}
+
+// Actor identifies who or what made a change, for the audit log
+type Actor struct {
+	Name      string
+	RequestID string
+}
+
+type AuditEntry struct {
+	ID         string    `db:"ID"`
+	EntityType string    `db:"EntityType"`
+	EntityID   string    `db:"EntityID"`
+	Action     string    `db:"Action"`
+	OldValue   *string   `db:"OldValue"`
+	NewValue   *string   `db:"NewValue"`
+	Actor      string    `db:"Actor"`
+	RequestID  string    `db:"RequestID"`
+	PrevHash   *string   `db:"PrevHash"`
+	Hash       *string   `db:"Hash"`
+	CreateDate Timestamp `db:"CreateDate"`
+}
+
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	Actor      string
+}
+
+type AuditVerification struct {
+	Valid         bool
+	CheckedCount  int
+	FirstBrokenID string `json:",omitempty"`
+}
+
+type LedgerJournal struct {
+	ID          string    `db:"ID"`
+	OrderID     string    `db:"OrderID"`
+	Description string    `db:"Description"`
+	CreateDate  Timestamp `db:"CreateDate"`
+}
+
+// LedgerEntry is one side of a journal. Amount is positive for a debit and negative for a credit.
+type LedgerEntry struct {
+	ID        string  `db:"ID"`
+	JournalID string  `db:"JournalID"`
+	Account   string  `db:"Account"`
+	OrderID   string  `db:"OrderID"`
+	Amount    float64 `db:"Amount"`
+}
+
+type LedgerBalance struct {
+	Account string  `db:"Account"`
+	Balance float64 `db:"Balance"`
+}
+
+type LedgerCheck struct {
+	Balanced           bool
+	Total              float64
+	UnbalancedJournals []string
+	MismatchedOrders   []string
+	Balances           []*LedgerBalance
+}
+
+// ChainTransfer is a Transfer event emitted by the staking contract, with the value in MBLX
+type ChainTransfer struct {
+	TXHash      string
+	From        string
+	To          string
 	Amount      float64
-	UserAddress string
-	UserDID     string
-	ProductID   string
+	BlockNumber uint64
+	LogIndex    uint
+}
+
+type ReconciliationFinding struct {
+	Kind        string
+	TXHash      string
+	PaymentNo   string
+	OrderID     string
+	TXType      string
+	DBAmount    float64
+	ChainAmount float64
+	BlockNumber uint64
+	Detail      string
+}
+
+type ReconciliationReport struct {
+	FromBlock      uint64
+	ToBlock        uint64
+	From           Timestamp
+	To             Timestamp
+	ChainTransfers int
+	DBTransactions int
+	Findings       []*ReconciliationFinding
+}
+
+type ReconciliationTicket struct {
+	ID         string    `db:"ID"`
+	Kind       string    `db:"Kind"`
+	TXHash     string    `db:"TXHash"`
+	PaymentNo  string    `db:"PaymentNo"`
+	Detail     string    `db:"Detail"`
+	Status     string    `db:"Status"`
+	CreateDate Timestamp `db:"CreateDate"`
+}
+
+type TreasuryStatus struct {
+	Balance             float64
+	Liabilities         float64
+	CoverageRatio       float64
+	MinCoverageRatio    float64
+	UnderCollateralized bool
+	CheckedAt           Timestamp
+	LastError           string `json:",omitempty"`
+}
+
+// PayoutRequest is a redemption held back while the treasury is under-collateralized
+type PayoutRequest struct {
+	ID         string    `db:"ID"`
+	OrderID    string    `db:"OrderID"`
+	PayoutType string    `db:"PayoutType"`
+	Amount     float64   `db:"Amount"`
+	Status     string    `db:"Status"`
+	Actor      string    `db:"Actor"`
+	RequestID  string    `db:"RequestID"`
+	Detail     string    `db:"Detail"`
+	CreateDate Timestamp `db:"CreateDate"`
+	UpdateDate Timestamp `db:"UpdateDate"`
+}
+
+type PayoutQueuedOutput struct {
+	PayoutID string
+	Status   string
+	Amount   float64
+}
+
+const HealthStatusOK = "ok"
+const HealthStatusFailing = "failing"
+
+type HealthCheck struct {
+	Status string
+	Detail string `json:",omitempty"`
+}
+
+type JobHeartbeat struct {
+	Name     string
+	Interval string
+	LastRun  Timestamp
+	Healthy  bool
+}
+
+type HealthReport struct {
+	Status string
+	Checks map[string]*HealthCheck
+	Jobs   []*JobHeartbeat
+}
+
+type ProductInput struct {
+	ProductName    string    `binding:"required"`
+	MinOrderValue  int       `binding:"gte=0"`
+	TopUpLimit     float64   `binding:"required,gt=0"`
+	MinRedeemValue int       `binding:"gte=0"`
+	LockUpPeriod   int       `binding:"gte=0"`
+	Term           int       `binding:"required,gt=0"`
+	DefaultAPY     float64   `binding:"gte=0"`
+	StartDate      Timestamp `binding:"required"`
+}
+
+type IDParam struct {
+	ID string `uri:"id" binding:"required,numeric_id"`
+}
+
+type DIDParam struct {
+	DID string `uri:"did" binding:"required,did"`
+}
+
+type RenewalPreferenceInput struct {
+	AutoRenew        bool
+	CompoundInterest bool
 }
 
 type CreateOrderOutput struct {
@@ -117,22 +393,80 @@ type CreateOrderOutput struct {
 }
 
 type SubmitBuyinInput struct {
-	OrderID string
-	TxHash  string
+	OrderID string `binding:"required,numeric_id"`
+	TxHash  string `binding:"required,tx_hash"`
 }
 
 type SubmitBuyinOutput struct {
 	ProductName    string
 	Amount         float64
-	Time           string
+	Time           Timestamp
 	UserAddress    string
 	TXCurrencyType string
 }
 
+type TopUpOrderInput struct {
+	OrderID string  `binding:"required,numeric_id"`
+	Amount  float64 `binding:"required,gt=0"`
+}
+
+type TopUpOrderOutput struct {
+	OrderID        string
+	PaymentAddress string
+	Amount         float64
+	NewPrincipal   float64
+}
+
+type SubmitTopUpInput struct {
+	OrderID string  `binding:"required,numeric_id"`
+	Amount  float64 `binding:"required,gt=0"`
+	TxHash  string  `binding:"required,tx_hash"`
+}
+
+type SubmitTopUpOutput struct {
+	ProductName    string
+	Amount         float64
+	NewPrincipal   float64
+	Time           Timestamp
+	UserAddress    string
+	TXCurrencyType string
+}
+
+type EarlyRedemptionQuote struct {
+	OrderID           string
+	Principal         float64
+	AccruedInterest   float64
+	PenaltyRate       float64
+	ForfeitedInterest float64
+	PayoutInterest    float64
+	TotalPayout       float64
+	LockUpEndTime     Timestamp
+	RedeemableTime    Timestamp
+}
+
+type EarlyRedeemOrderOutput struct {
+	ProductName       string
+	Amount            float64
+	ForfeitedInterest float64
+	Time              Timestamp
+	ToAddress         string
+	TXCurrencyType    string
+	TXHash            string
+}
+
+type AdvanceClockInput struct {
+	Duration string `binding:"required,duration"`
+}
+
+type ClockOutput struct {
+	Now    Timestamp
+	Offset string
+}
+
 type RedeemOrderOuput struct {
 	ProductName    string
 	Amount         float64
-	Time           string
+	Time           Timestamp
 	ToAddress      string
 	TXCurrencyType string
 	TXHash         string
@@ -146,6 +480,95 @@ func NewStakingProduct() *StakingProduct {
 	return &StakingProduct{}
 }
 
+func NewProductHistory() *ProductHistory {
+	return &ProductHistory{}
+}
+
+func NewProductInput() *ProductInput {
+	return &ProductInput{}
+}
+
+func NewLedgerJournal() *LedgerJournal {
+	return &LedgerJournal{}
+}
+
+func NewLedgerEntry(account, orderID string, amount float64) *LedgerEntry {
+	return &LedgerEntry{
+		Account: account,
+		OrderID: orderID,
+		Amount:  amount,
+	}
+}
+
+func NewLedgerCheck() *LedgerCheck {
+	return &LedgerCheck{}
+}
+
+func NewChainTransfer() *ChainTransfer {
+	return &ChainTransfer{}
+}
+
+func NewReconciliationFinding(kind string) *ReconciliationFinding {
+	return &ReconciliationFinding{Kind: kind}
+}
+
+func NewReconciliationReport() *ReconciliationReport {
+	return &ReconciliationReport{}
+}
+
+func NewReconciliationTicket() *ReconciliationTicket {
+	return &ReconciliationTicket{}
+}
+
+func NewTreasuryStatus() *TreasuryStatus {
+	return &TreasuryStatus{}
+}
+
+func NewPayoutRequest() *PayoutRequest {
+	return &PayoutRequest{}
+}
+
+func NewPayoutQueuedOutput() *PayoutQueuedOutput {
+	return &PayoutQueuedOutput{}
+}
+
+func NewHealthCheck(status, detail string) *HealthCheck {
+	return &HealthCheck{
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func NewJobHeartbeat(name string) *JobHeartbeat {
+	return &JobHeartbeat{Name: name}
+}
+
+func NewHealthReport() *HealthReport {
+	return &HealthReport{
+		Status: HealthStatusOK,
+		Checks: make(map[string]*HealthCheck),
+	}
+}
+
+func NewActor(name, requestID string) *Actor {
+	return &Actor{
+		Name:      name,
+		RequestID: requestID,
+	}
+}
+
+func NewAuditEntry() *AuditEntry {
+	return &AuditEntry{}
+}
+
+func NewAuditFilter() *AuditFilter {
+	return &AuditFilter{}
+}
+
+func NewAuditVerification() *AuditVerification {
+	return &AuditVerification{}
+}
+
 func NewUser() *User {
 	return &User{}
 }
@@ -158,6 +581,10 @@ func NewOrderInterest() *OrderInterest {
 	return &OrderInterest{}
 }
 
+func NewAccrualBasis() *AccrualBasis {
+	return &AccrualBasis{}
+}
+
 func NewStakingRecord() *StakingRecord {
 	return &StakingRecord{}
 }
@@ -170,6 +597,18 @@ func NewCreateOrderInput() *CreateOrderInput {
 	return &CreateOrderInput{}
 }
 
+func NewIDParam() *IDParam {
+	return &IDParam{}
+}
+
+func NewDIDParam() *DIDParam {
+	return &DIDParam{}
+}
+
+func NewRenewalPreferenceInput() *RenewalPreferenceInput {
+	return &RenewalPreferenceInput{}
+}
+
 func NewCreateOrderOutput() *CreateOrderOutput {
 	return &CreateOrderOutput{}
 }
@@ -182,6 +621,22 @@ func NewSubmitBuyinOutput() *SubmitBuyinOutput {
 	return &SubmitBuyinOutput{}
 }
 
+func NewTopUpOrderInput() *TopUpOrderInput {
+	return &TopUpOrderInput{}
+}
+
+func NewTopUpOrderOutput() *TopUpOrderOutput {
+	return &TopUpOrderOutput{}
+}
+
+func NewSubmitTopUpInput() *SubmitTopUpInput {
+	return &SubmitTopUpInput{}
+}
+
+func NewSubmitTopUpOutput() *SubmitTopUpOutput {
+	return &SubmitTopUpOutput{}
+}
+
 func NewOrderInterestInfo() *OrderInterestInfo {
 	return &OrderInterestInfo{}
 }
@@ -189,3 +644,34 @@ func NewOrderInterestInfo() *OrderInterestInfo {
 func NewRedeemOrderOutput() *RedeemOrderOuput {
 	return &RedeemOrderOuput{}
 }
+
+func NewIdempotencyRecord() *IdempotencyRecord {
+	return &IdempotencyRecord{}
+}
+
+func NewListOptions() *ListOptions {
+	return &ListOptions{}
+}
+
+func NewPage(items interface{}, nextCursor string) *Page {
+	return &Page{
+		Items:      items,
+		NextCursor: nextCursor,
+	}
+}
+
+func NewEarlyRedemptionQuote() *EarlyRedemptionQuote {
+	return &EarlyRedemptionQuote{}
+}
+
+func NewEarlyRedeemOrderOutput() *EarlyRedeemOrderOutput {
+	return &EarlyRedeemOrderOutput{}
+}
+
+func NewAdvanceClockInput() *AdvanceClockInput {
+	return &AdvanceClockInput{}
+}
+
+func NewClockOutput() *ClockOutput {
+	return &ClockOutput{}
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const rfc3339Millis = "2006-01-02T15:04:05.000Z07:00"
+const sqlTimeLayout = "2006-01-02 15:04:05"
+
+// Timestamp is the single representation of a point in time across the API and the database.
+// It is always held in UTC and is rendered as an RFC 3339 string with millisecond precision; see
+// WithEpochMillis for clients that also want epoch milliseconds. A zero Timestamp is NULL in both.
+type Timestamp struct {
+	time.Time
+}
+
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{t.UTC()}
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.UTC().Format(rfc3339Millis))
+}
+
+// EpochMillis returns the timestamp as milliseconds since the Unix epoch
+func (t Timestamp) EpochMillis() int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Timestamp{}
+		return nil
+	}
+	millis, err := strconv.ParseInt(string(data), 10, 64)
+	if err == nil {
+		*t = NewTimestamp(time.Unix(0, millis*int64(time.Millisecond)))
+		return nil
+	}
+	var value string
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return err
+	}
+	*t = NewTimestamp(parsed)
+	return nil
+}
+
+func (t *Timestamp) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case nil:
+		*t = Timestamp{}
+	case time.Time:
+		*t = NewTimestamp(value)
+	case []byte:
+		return t.parseSQL(string(value))
+	case string:
+		return t.parseSQL(value)
+	default:
+		return errors.New("unsupported timestamp value")
+	}
+	return nil
+}
+
+// parseSQL handles DATETIME columns read without parseTime; they are stored in UTC
+func (t *Timestamp) parseSQL(value string) error {
+	parsed, err := time.Parse(sqlTimeLayout, value)
+	if err != nil {
+		return err
+	}
+	*t = NewTimestamp(parsed)
+	return nil
+}
+
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampJSON(t *testing.T) {
+	moment := time.Date(2022, 3, 1, 12, 30, 15, 250*int(time.Millisecond), time.FixedZone("UTC+8", 8*60*60))
+	tests := []struct {
+		name string
+		in   Timestamp
+		want string
+	}{
+		{"rendered in UTC with milliseconds", NewTimestamp(moment), `"2022-03-01T04:30:15.250Z"`},
+		{"zero is null", Timestamp{}, `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %s, want %s", got, tt.want)
+			}
+			var back Timestamp
+			err = json.Unmarshal(got, &back)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !back.Equal(tt.in.Time) {
+				t.Errorf("round trip = %v, want %v", back, tt.in)
+			}
+		})
+	}
+}
+
+func TestWithEpochMillis(t *testing.T) {
+	moment := NewTimestamp(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC))
+	type embedded struct {
+		Embedded Timestamp
+	}
+	type record struct {
+		embedded
+		Name    string
+		Time    Timestamp
+		Unset   Timestamp
+		Skipped Timestamp `json:"-"`
+		Renamed Timestamp `json:"renamed"`
+		Empty   string    `json:",omitempty"`
+		Nested  *OrderInterest
+		hidden  Timestamp
+	}
+	in := &Page{
+		Items: []*record{{
+			embedded: embedded{Embedded: moment},
+			Name:     "a",
+			Time:     moment,
+			Skipped:  moment,
+			Renamed:  moment,
+			Nested:   &OrderInterest{ID: "1", Time: moment},
+			hidden:   moment,
+		}},
+		NextCursor: "next",
+	}
+
+	got, err := json.Marshal(WithEpochMillis(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"items":[{` +
+		`"Embedded":"2022-03-01T00:00:00.000Z","EmbeddedMillis":1646092800000,` +
+		`"Name":"a",` +
+		`"Nested":{"APY":0,"ID":"1","InterestGain":0,"OrderID":"","Time":"2022-03-01T00:00:00.000Z","TimeMillis":1646092800000,"TotalInterestGain":0},` +
+		`"Time":"2022-03-01T00:00:00.000Z","TimeMillis":1646092800000,` +
+		`"Unset":null,"UnsetMillis":null,` +
+		`"renamed":"2022-03-01T00:00:00.000Z","renamedMillis":1646092800000` +
+		`}],"next_cursor":"next"}`
+	if string(got) != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	if WithEpochMillis(nil) != nil {
+		t.Error("WithEpochMillis(nil) is not nil")
+	}
+}
@@ -0,0 +1,156 @@
+package payouts
+
+import (
+	"context"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/treasury"
+)
+
+const queueActor = "system:payouts"
+
+// RedeemOrder completes an order and pays out its principal and unharvested interest. The order
+// is claimed before the payout is sent, so a redemption that loses a race pays nothing; a payout
+// that fails to send leaves its transaction without a hash for reconciliation to flag. Callers
+// are responsible for checking that the order may be redeemed.
+func RedeemOrder(ctx context.Context, actor *models.Actor, orderID string) (*models.RedeemOrderOuput, error) {
+	productName, err := dao.GetProductNameForOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	txInfo := models.NewTXInfo()
+	txInfo.OrderID = orderID
+	txInfo.TXCurrencyType = "MBLX"
+	txInfo.TXType = "Redeem"
+	err = dao.RedeemOrder(ctx, actor, txInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash := contract.RedeemOrder(ctx)
+	err = dao.SetTXHash(ctx, actor, txInfo.PaymentNo, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	output := models.NewRedeemOrderOutput()
+	output.Amount = txInfo.Principal + txInfo.Interest
+	output.ProductName = productName
+	output.TXCurrencyType = "MBLX"
+	output.TXHash = txHash
+	output.Time = models.NewTimestamp(clock.Now())
+	output.ToAddress = txInfo.UserAddress
+	return output, nil
+}
+
+// RedeemInterest pays out an order's unharvested interest. As with RedeemOrder, the interest is
+// claimed before the payout is sent.
+func RedeemInterest(ctx context.Context, actor *models.Actor, orderID string) (*models.RedeemOrderOuput, error) {
+	productName, err := dao.GetProductNameForOrder(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	txInfo := models.NewTXInfo()
+	txInfo.OrderID = orderID
+	txInfo.TXCurrencyType = "MBLX"
+	txInfo.TXType = "Harvest"
+	txInfo.RedeemableTime = models.NewTimestamp(clock.Now())
+	err = dao.HarvestInterest(ctx, actor, txInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash := contract.RedeemInterest(ctx)
+	err = dao.SetTXHash(ctx, actor, txInfo.PaymentNo, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	output := models.NewRedeemOrderOutput()
+	output.Amount = txInfo.Interest
+	output.ProductName = productName
+	output.TXCurrencyType = "MBLX"
+	output.TXHash = txHash
+	output.Time = models.NewTimestamp(clock.Now())
+	output.ToAddress = txInfo.UserAddress
+	return output, nil
+}
+
+// Queue holds back a payout that was requested while the treasury is under-collateralized
+func Queue(ctx context.Context, actor *models.Actor, orderID, payoutType string, amount float64) (*models.PayoutQueuedOutput, error) {
+	payout := models.NewPayoutRequest()
+	payout.OrderID = orderID
+	payout.PayoutType = payoutType
+	payout.Amount = amount
+	payout.Actor = actor.Name
+	payout.RequestID = actor.RequestID
+
+	id, err := dao.QueuePayout(ctx, payout)
+	if err != nil {
+		return nil, err
+	}
+
+	output := models.NewPayoutQueuedOutput()
+	output.PayoutID = id
+	output.Status = models.PayoutStatusQueued
+	output.Amount = amount
+	return output, nil
+}
+
+// ProcessQueue pays out queued requests in the order they were made, as long as the treasury is
+// covered. The request was validated when it was queued, so only the order's status is checked.
+func ProcessQueue(ctx context.Context) error {
+	if treasury.UnderCollateralized() {
+		return nil
+	}
+	queued, err := dao.GetPayouts(ctx, models.PayoutStatusQueued)
+	if err != nil {
+		return err
+	}
+
+	for _, payout := range queued {
+		actor := models.NewActor(queueActor, payout.RequestID)
+		err = pay(ctx, actor, payout)
+		status := models.PayoutStatusPaid
+		detail := ""
+		if err != nil {
+			//keep going so that one bad payout does not hold up the rest
+			logger.Error("failed to pay queued payout " + payout.ID + ": " + err.Error())
+			status = models.PayoutStatusFailed
+			detail = apperrors.Message(err)
+		}
+		err = dao.UpdatePayoutStatus(ctx, payout.ID, status, detail)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func pay(ctx context.Context, actor *models.Actor, payout *models.PayoutRequest) error {
+	order, err := dao.GetOrderByID(ctx, payout.OrderID)
+	if err != nil {
+		return err
+	}
+	if order.Type != models.OrderTypeHolding && order.Type != models.OrderTypeMatured {
+		return apperrors.Conflict("order is no longer holding")
+	}
+
+	switch payout.PayoutType {
+	case models.PayoutTypeRedeem:
+		_, err = RedeemOrder(ctx, actor, payout.OrderID)
+	case models.PayoutTypeHarvest:
+		_, err = RedeemInterest(ctx, actor, payout.OrderID)
+	default:
+		err = apperrors.Validation("unknown payout type " + payout.PayoutType)
+	}
+	return err
+}
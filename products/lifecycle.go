@@ -0,0 +1,148 @@
+package products
+
+import (
+	"context"
+	"time"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+// SystemOperator is recorded in the product history for transitions made by the scheduler
+const SystemOperator = "system"
+
+// Products move through these states:
+//
+//	Upcoming -> Open        when StartDate is reached
+//	Open     -> SoldOut     when the staked principal reaches TopUpLimit
+//	SoldOut  -> Open        when principal is redeemed and capacity frees up again
+//	any      -> Closed      when an operator pauses or retires the product
+//	Closed   -> scheduled   when an operator resumes a paused product
+//	Closed   -> Settled     once a retired product has no orders left that are not complete
+//
+// Only Open products accept new orders.
+
+var states = map[string]bool{
+	models.ProductStateUpcoming: true,
+	models.ProductStateOpen:     true,
+	models.ProductStateSoldOut:  true,
+	models.ProductStateClosed:   true,
+	models.ProductStateSettled:  true,
+}
+
+func IsValidState(state string) bool {
+	return states[state]
+}
+
+// ScheduledState returns the state a product should be in at the given time for the principal
+// currently staked in it. Closed and Settled products are only moved by operators, apart from a
+// retired product settling once its orders are complete.
+func ScheduledState(product *models.StakingProduct, totalPrincipal float64, liveOrders int, now time.Time) string {
+	switch product.State {
+	case models.ProductStateSettled:
+		return models.ProductStateSettled
+	case models.ProductStateClosed:
+		if product.Retired && liveOrders == 0 {
+			return models.ProductStateSettled
+		}
+		return models.ProductStateClosed
+	}
+	return openState(product, totalPrincipal, now)
+}
+
+func openState(product *models.StakingProduct, totalPrincipal float64, now time.Time) string {
+	if now.Before(product.StartDate.Time) {
+		return models.ProductStateUpcoming
+	}
+	if totalPrincipal >= product.TopUpLimit {
+		return models.ProductStateSoldOut
+	}
+	return models.ProductStateOpen
+}
+
+// InitialState is the state a newly created product starts in
+func InitialState(product *models.StakingProduct) string {
+	return openState(product, 0, clock.Now())
+}
+
+// Transition returns the product as it would be after an operator action
+func Transition(ctx context.Context, product *models.StakingProduct, action string) (*models.StakingProduct, error) {
+	if product.Retired || product.State == models.ProductStateSettled {
+		return nil, apperrors.Conflict("product has been retired and can no longer be changed")
+	}
+
+	updated := *product
+	switch action {
+	case models.ProductActionPause:
+		if product.State == models.ProductStateClosed {
+			return nil, apperrors.Conflict("product is already closed")
+		}
+		updated.State = models.ProductStateClosed
+	case models.ProductActionResume:
+		if product.State != models.ProductStateClosed {
+			return nil, apperrors.Conflict("only closed products can be resumed")
+		}
+		totalPrincipal, err := dao.GetProductTotalPrincipal(ctx, product.ID)
+		if err != nil {
+			return nil, err
+		}
+		updated.State = openState(product, totalPrincipal, clock.Now())
+	case models.ProductActionRetire:
+		updated.State = models.ProductStateClosed
+		updated.Retired = true
+	default:
+		return nil, apperrors.Validation("unknown product action " + action)
+	}
+	return &updated, nil
+}
+
+// Refresh applies any scheduled transition that is due for the product
+func Refresh(ctx context.Context, productID string) error {
+	product, err := dao.GetStakingProductByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	return refresh(ctx, product)
+}
+
+// RefreshAll applies due scheduled transitions to every product that has not been settled
+func RefreshAll(ctx context.Context) error {
+	products, err := dao.GetActiveStakingProducts(ctx)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, product := range products {
+		err = refresh(ctx, product)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func refresh(ctx context.Context, product *models.StakingProduct) error {
+	totalPrincipal, err := dao.GetProductTotalPrincipal(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+	liveOrders, err := dao.CountLiveOrdersForProduct(ctx, product.ID)
+	if err != nil {
+		return err
+	}
+
+	state := ScheduledState(product, totalPrincipal, liveOrders, clock.Now())
+	if state == product.State {
+		return nil
+	}
+	updated := *product
+	updated.State = state
+	err = dao.SetProductState(ctx, product, &updated, models.ProductActionSchedule, SystemOperator)
+	if apperrors.KindOf(err) == apperrors.KindConflict {
+		//someone else moved the product first; the next run will pick up from the new state
+		return nil
+	}
+	return err
+}
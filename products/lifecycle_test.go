@@ -0,0 +1,109 @@
+package products
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+)
+
+func TestScheduledState(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	product := func(state string, retired bool, start time.Time) *models.StakingProduct {
+		return &models.StakingProduct{
+			State:      state,
+			Retired:    retired,
+			TopUpLimit: 1000,
+			StartDate:  models.NewTimestamp(start),
+		}
+	}
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name           string
+		product        *models.StakingProduct
+		totalPrincipal float64
+		liveOrders     int
+		want           string
+	}{
+		{"upcoming before the start date", product(models.ProductStateUpcoming, false, after), 0, 0, models.ProductStateUpcoming},
+		{"opens at the start date", product(models.ProductStateUpcoming, false, now), 0, 0, models.ProductStateOpen},
+		{"sells out at the limit", product(models.ProductStateOpen, false, before), 1000, 3, models.ProductStateSoldOut},
+		{"stays open below the limit", product(models.ProductStateOpen, false, before), 999, 3, models.ProductStateOpen},
+		{"reopens when principal is redeemed", product(models.ProductStateSoldOut, false, before), 500, 2, models.ProductStateOpen},
+		{"paused product stays closed", product(models.ProductStateClosed, false, before), 0, 0, models.ProductStateClosed},
+		{"retired product waits for its orders", product(models.ProductStateClosed, true, before), 0, 1, models.ProductStateClosed},
+		{"retired product settles once its orders are complete", product(models.ProductStateClosed, true, before), 0, 0, models.ProductStateSettled},
+		{"settled product stays settled", product(models.ProductStateSettled, true, after), 0, 0, models.ProductStateSettled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScheduledState(tt.product, tt.totalPrincipal, tt.liveOrders, now)
+			if got != tt.want {
+				t.Errorf("ScheduledState() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransition(t *testing.T) {
+	started := models.NewTimestamp(time.Now().Add(-time.Hour))
+	product := func(state string, retired bool) *models.StakingProduct {
+		return &models.StakingProduct{ID: "1", State: state, Retired: retired, TopUpLimit: 1000, StartDate: started}
+	}
+
+	tests := []struct {
+		name           string
+		product        *models.StakingProduct
+		action         string
+		totalPrincipal float64
+		wantState      string
+		wantRetired    bool
+		wantErr        bool
+		wantKind       apperrors.Kind
+	}{
+		{name: "pause an open product", product: product(models.ProductStateOpen, false), action: models.ProductActionPause, wantState: models.ProductStateClosed},
+		{name: "pause a closed product", product: product(models.ProductStateClosed, false), action: models.ProductActionPause, wantErr: true, wantKind: apperrors.KindConflict},
+		{name: "resume into open", product: product(models.ProductStateClosed, false), action: models.ProductActionResume, totalPrincipal: 10, wantState: models.ProductStateOpen},
+		{name: "resume into sold out", product: product(models.ProductStateClosed, false), action: models.ProductActionResume, totalPrincipal: 1000, wantState: models.ProductStateSoldOut},
+		{name: "resume an open product", product: product(models.ProductStateOpen, false), action: models.ProductActionResume, wantErr: true, wantKind: apperrors.KindConflict},
+		{name: "retire an open product", product: product(models.ProductStateOpen, false), action: models.ProductActionRetire, wantState: models.ProductStateClosed, wantRetired: true},
+		{name: "change a retired product", product: product(models.ProductStateClosed, true), action: models.ProductActionResume, wantErr: true, wantKind: apperrors.KindConflict},
+		{name: "change a settled product", product: product(models.ProductStateSettled, true), action: models.ProductActionPause, wantErr: true, wantKind: apperrors.KindConflict},
+		{name: "unknown action", product: product(models.ProductStateOpen, false), action: "Delete", wantErr: true, wantKind: apperrors.KindValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dao.SqlDB = sqlx.NewDb(db, "mysql")
+			mock.ExpectQuery(regexp.QuoteMeta("select coalesce(sum(Amount), 0) from Orders")).WithArgs("1").
+				WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(tt.totalPrincipal))
+
+			updated, err := Transition(context.Background(), tt.product, tt.action)
+			if tt.wantErr {
+				if apperrors.KindOf(err) != tt.wantKind {
+					t.Fatalf("error = %v, want kind %v", err, tt.wantKind)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.State != tt.wantState || updated.Retired != tt.wantRetired {
+				t.Errorf("Transition() = %s retired %v, want %s retired %v", updated.State, updated.Retired, tt.wantState, tt.wantRetired)
+			}
+		})
+	}
+}
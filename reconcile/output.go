@@ -0,0 +1,43 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/metabloxStaking/models"
+)
+
+func WriteJSON(w io.Writer, report *models.ReconciliationReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteCSV writes one row per finding
+func WriteCSV(w io.Writer, report *models.ReconciliationReport) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"Kind", "TXHash", "PaymentNo", "OrderID", "TXType", "DBAmount", "ChainAmount", "BlockNumber", "Detail"})
+	if err != nil {
+		return err
+	}
+	for _, finding := range report.Findings {
+		err = writer.Write([]string{
+			finding.Kind,
+			finding.TXHash,
+			finding.PaymentNo,
+			finding.OrderID,
+			finding.TXType,
+			strconv.FormatFloat(finding.DBAmount, 'f', -1, 64),
+			strconv.FormatFloat(finding.ChainAmount, 'f', -1, 64),
+			strconv.FormatUint(finding.BlockNumber, 10),
+			finding.Detail,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
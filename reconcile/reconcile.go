@@ -0,0 +1,217 @@
+package reconcile
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+const defaultTimeSlack = 10 * time.Minute
+
+const defaultBlockBatch = 2000
+
+// amountTolerance absorbs rounding between the float amounts in the database and token units on chain
+const amountTolerance = 1e-6
+
+// Run compares the staking contract's Transfer events in a block range with the transactions
+// recorded in the database over the same period. Events are fetched reconcile.blockBatch blocks at
+// a time. Transactions are recorded once the chain transaction has completed, so database rows up
+// to reconcile.timeSlack after the last block are also searched when looking for an event's
+// transaction.
+func Run(ctx context.Context, fromBlock, toBlock uint64) (*models.ReconciliationReport, error) {
+	if toBlock < fromBlock {
+		return nil, apperrors.Validation("the end block must not be before the start block")
+	}
+	fromTime, err := contract.BlockTime(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	toTime, err := contract.BlockTime(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime := settings.Current()
+	batch := runtime.ReconcileBlockBatch
+	if batch == 0 {
+		batch = defaultBlockBatch
+	}
+	var transfers []*models.ChainTransfer
+	for _, blocks := range blockRanges(fromBlock, toBlock, batch) {
+		found, err := contract.FilterTransfers(ctx, blocks[0], blocks[1])
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, found...)
+	}
+	slack := runtime.ReconcileTimeSlack
+	if slack <= 0 {
+		slack = defaultTimeSlack
+	}
+	transactions, err := dao.GetTransferTransactions(ctx, fromTime, toTime.Add(slack))
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.NewReconciliationReport()
+	report.FromBlock = fromBlock
+	report.ToBlock = toBlock
+	report.From = models.NewTimestamp(fromTime)
+	report.To = models.NewTimestamp(toTime)
+	report.ChainTransfers = len(transfers)
+	report.Findings = compare(transfers, transactions, toTime)
+	for _, tx := range transactions {
+		if !tx.CreateDate.After(toTime) {
+			report.DBTransactions++
+		}
+	}
+	return report, nil
+}
+
+// blockRanges splits [from, to] into consecutive inclusive ranges of at most size blocks
+func blockRanges(from, to, size uint64) [][2]uint64 {
+	var ranges [][2]uint64
+	for start := from; start <= to; start += size {
+		end := to
+		if to-start >= size {
+			end = start + size - 1
+		}
+		ranges = append(ranges, [2]uint64{start, end})
+		if end == to {
+			break
+		}
+	}
+	return ranges
+}
+
+func compare(transfers []*models.ChainTransfer, transactions []*models.TXInfo, toTime time.Time) []*models.ReconciliationFinding {
+	var findings []*models.ReconciliationFinding
+
+	//a single chain transaction can emit several Transfer events
+	chainAmounts := make(map[string]float64)
+	chainBlocks := make(map[string]uint64)
+	for _, transfer := range transfers {
+		hash := normalizeHash(transfer.TXHash)
+		chainAmounts[hash] += transfer.Amount
+		chainBlocks[hash] = transfer.BlockNumber
+	}
+
+	recorded := make(map[string]*models.TXInfo)
+	for _, tx := range transactions {
+		hash := ""
+		if tx.TXHash != nil {
+			hash = normalizeHash(*tx.TXHash)
+		}
+		//rows after the range are only used to match events near its end
+		inRange := !tx.CreateDate.After(toTime)
+
+		if !isTXHash(hash) {
+			if inRange {
+				finding := newFinding(models.FindingMissingOnChain, tx)
+				finding.Detail = "no valid transaction hash recorded"
+				findings = append(findings, finding)
+			}
+			continue
+		}
+		if first, ok := recorded[hash]; ok {
+			if inRange {
+				finding := newFinding(models.FindingDuplicate, tx)
+				finding.Detail = "transaction hash is also recorded as payment " + first.PaymentNo
+				findings = append(findings, finding)
+			}
+			continue
+		}
+		recorded[hash] = tx
+		if !inRange {
+			continue
+		}
+
+		chainAmount, onChain := chainAmounts[hash]
+		if !onChain {
+			finding := newFinding(models.FindingMissingOnChain, tx)
+			finding.Detail = "no Transfer event found in the block range"
+			findings = append(findings, finding)
+			continue
+		}
+		dbAmount := tx.Principal + tx.Interest
+		if math.Abs(dbAmount-chainAmount) > amountTolerance {
+			finding := newFinding(models.FindingAmountMismatch, tx)
+			finding.ChainAmount = chainAmount
+			finding.BlockNumber = chainBlocks[hash]
+			finding.Detail = "recorded amount differs from the amount transferred on chain"
+			findings = append(findings, finding)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, transfer := range transfers {
+		hash := normalizeHash(transfer.TXHash)
+		if recorded[hash] != nil || seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		finding := models.NewReconciliationFinding(models.FindingMissingInDB)
+		finding.TXHash = transfer.TXHash
+		finding.ChainAmount = chainAmounts[hash]
+		finding.BlockNumber = transfer.BlockNumber
+		finding.Detail = "Transfer from " + transfer.From + " to " + transfer.To + " has no recorded transaction"
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func newFinding(kind string, tx *models.TXInfo) *models.ReconciliationFinding {
+	finding := models.NewReconciliationFinding(kind)
+	if tx.TXHash != nil {
+		finding.TXHash = *tx.TXHash
+	}
+	finding.PaymentNo = tx.PaymentNo
+	finding.OrderID = tx.OrderID
+	finding.TXType = tx.TXType
+	finding.DBAmount = tx.Principal + tx.Interest
+	return finding
+}
+
+func normalizeHash(hash string) string {
+	return strings.ToLower(strings.TrimSpace(hash))
+}
+
+func isTXHash(hash string) bool {
+	if len(hash) != 66 || !strings.HasPrefix(hash, "0x") {
+		return false
+	}
+	for _, r := range hash[2:] {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// OpenTickets records a remediation ticket for every finding that does not already have one,
+// returning how many were opened
+func OpenTickets(ctx context.Context, report *models.ReconciliationReport) (int, error) {
+	opened := 0
+	for _, finding := range report.Findings {
+		ticket := models.NewReconciliationTicket()
+		ticket.Kind = finding.Kind
+		ticket.TXHash = finding.TXHash
+		ticket.PaymentNo = finding.PaymentNo
+		ticket.Detail = finding.Detail
+		created, err := dao.OpenReconciliationTicket(ctx, ticket)
+		if err != nil {
+			return opened, err
+		}
+		if created {
+			opened++
+		}
+	}
+	return opened, nil
+}
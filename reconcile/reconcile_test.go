@@ -0,0 +1,130 @@
+package reconcile
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metabloxStaking/models"
+)
+
+func TestCompare(t *testing.T) {
+	toTime := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	hashA := "0x" + strings.Repeat("a", 64)
+	hashB := "0x" + strings.Repeat("b", 64)
+	tx := func(paymentNo string, hash string, principal float64, created time.Time) *models.TXInfo {
+		tx := models.NewTXInfo()
+		tx.PaymentNo = paymentNo
+		if hash != "" {
+			tx.TXHash = &hash
+		}
+		tx.Principal = principal
+		tx.CreateDate = models.NewTimestamp(created)
+		return tx
+	}
+	transfer := func(hash string, amount float64) *models.ChainTransfer {
+		return &models.ChainTransfer{TXHash: hash, Amount: amount, BlockNumber: 9}
+	}
+	inRange := toTime.Add(-time.Minute)
+	afterRange := toTime.Add(time.Minute)
+
+	tests := []struct {
+		name         string
+		transfers    []*models.ChainTransfer
+		transactions []*models.TXInfo
+		want         []string
+	}{
+		{
+			name:         "matched",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 100)},
+			transactions: []*models.TXInfo{tx("1", hashA, 100, inRange)},
+		},
+		{
+			name:         "hash recorded without its 0x prefix",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 100)},
+			transactions: []*models.TXInfo{tx("1", strings.ToUpper(hashA[2:]), 100, inRange)},
+			want:         []string{models.FindingMissingOnChain + " 1", models.FindingMissingInDB + " " + hashA},
+		},
+		{
+			name:         "amount summed over several events, hash in another case",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 60), transfer(hashA, 40)},
+			transactions: []*models.TXInfo{tx("1", " "+strings.ToUpper("0x"+strings.Repeat("a", 64))+" ", 100, inRange)},
+		},
+		{
+			name:         "amount mismatch",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 90)},
+			transactions: []*models.TXInfo{tx("1", hashA, 100, inRange)},
+			want:         []string{models.FindingAmountMismatch + " 1"},
+		},
+		{
+			name:         "no hash recorded",
+			transactions: []*models.TXInfo{tx("1", "", 100, inRange)},
+			want:         []string{models.FindingMissingOnChain + " 1"},
+		},
+		{
+			name:         "no event for the hash",
+			transactions: []*models.TXInfo{tx("1", hashA, 100, inRange)},
+			want:         []string{models.FindingMissingOnChain + " 1"},
+		},
+		{
+			name:         "hash recorded twice",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 100)},
+			transactions: []*models.TXInfo{tx("1", hashA, 100, inRange), tx("2", hashA, 100, inRange)},
+			want:         []string{models.FindingDuplicate + " 2"},
+		},
+		{
+			name:      "event with no transaction",
+			transfers: []*models.ChainTransfer{transfer(hashB, 5), transfer(hashB, 5)},
+			want:      []string{models.FindingMissingInDB + " " + hashB},
+		},
+		{
+			name:         "event matched by a transaction recorded after the range",
+			transfers:    []*models.ChainTransfer{transfer(hashA, 100)},
+			transactions: []*models.TXInfo{tx("1", hashA, 100, afterRange)},
+		},
+		{
+			name:         "transaction after the range is not reported",
+			transactions: []*models.TXInfo{tx("1", hashA, 100, afterRange), tx("2", "", 100, afterRange)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, finding := range compare(tt.transfers, tt.transactions, toTime) {
+				id := finding.PaymentNo
+				if finding.Kind == models.FindingMissingInDB {
+					id = finding.TXHash
+				}
+				got = append(got, finding.Kind+" "+id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compare() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to uint64
+		size     uint64
+		want     [][2]uint64
+	}{
+		{"single block", 5, 5, 10, [][2]uint64{{5, 5}}},
+		{"smaller than a batch", 100, 150, 100, [][2]uint64{{100, 150}}},
+		{"exactly one batch", 100, 199, 100, [][2]uint64{{100, 199}}},
+		{"partial last batch", 100, 250, 100, [][2]uint64{{100, 199}, {200, 250}}},
+		{"batch of one block", 7, 9, 1, [][2]uint64{{7, 7}, {8, 8}, {9, 9}}},
+		{"ends at the highest block", math.MaxUint64 - 2, math.MaxUint64, 2, [][2]uint64{{math.MaxUint64 - 2, math.MaxUint64 - 1}, {math.MaxUint64, math.MaxUint64}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blockRanges(tt.from, tt.to, tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blockRanges(%d, %d, %d) = %v, want %v", tt.from, tt.to, tt.size, got, tt.want)
+			}
+		})
+	}
+}
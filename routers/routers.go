@@ -1,24 +1,80 @@
 package routers
 
 import (
+	"fmt"
+
 	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+
+	"github.com/metabloxStaking/clock"
 	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/middleware"
+	"github.com/metabloxStaking/tracing"
 )
 
-func Setup() {
+// Setup builds the router with every route registered. Client IPs, used for rate limiting and
+// logging, are only taken from X-Forwarded-For when the request comes through one of
+// server.trustedProxies.
+func Setup() (*gin.Engine, error) {
 	r := gin.New()
+	err := r.SetTrustedProxies(viper.GetStringSlice("server.trustedProxies"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid server.trustedProxies: %w", err)
+	}
+	r.Use(otelgin.Middleware(tracing.ServiceName()), middleware.RequestID(), middleware.AccessLog(), middleware.Recovery(), middleware.Metrics())
+
+	r.GET("/healthz", controllers.HealthzHandler)
+	r.GET("/readyz", controllers.ReadyzHandler)
+
+	//added after the probe routes so that those are never limited
+	r.Use(middleware.RateLimit())
+
+	idempotent := middleware.Idempotency()
 
 	r.GET("/product/search/:id", controllers.GetProductInfoByIDHandler)
 
 	r.GET("/product/all", controllers.GetAllProductInfoHandler)
-	r.POST("/order/create", controllers.CreateOrderHandler)
-	r.POST("/order/confirm", controllers.SubmitBuyinHandler)
+	r.POST("/order/create", idempotent, controllers.CreateOrderHandler)
+	r.POST("/order/confirm", idempotent, controllers.SubmitBuyinHandler)
+	r.POST("/order/renewal/:id", idempotent, controllers.SetRenewalPreferenceHandler)
+	r.POST("/order/topup", idempotent, controllers.TopUpOrderHandler)
+	r.POST("/order/topup/confirm", idempotent, controllers.SubmitTopUpHandler)
 
 	r.GET("/staking/orders/:did", controllers.GetStakingRecordsHandler)
 	r.GET("/staking/transactions/order/:id", controllers.GetTransactionsByOrderIDHandler)
-	r.GET("/staking/transactions/user/:id", controllers.GetTransactionsByUserDIDHandler)
+	r.GET("/staking/transactions/user/:did", controllers.GetTransactionsByUserDIDHandler)
 	r.GET("/staking/interest/:id", controllers.GetOrderInterestHandler)
-	r.POST("/staking/redeem/full/:id", controllers.RedeemOrderHandler)
-	r.POST("/staking/redeem/interest/:id", controllers.RedeemInterestHandler)
-	r.Run(":8889")
+	r.POST("/staking/redeem/full/:id", idempotent, controllers.RedeemOrderHandler)
+	r.POST("/staking/redeem/interest/:id", idempotent, controllers.RedeemInterestHandler)
+	r.GET("/staking/redeem/early/quote/:id", controllers.GetEarlyRedemptionQuoteHandler)
+	r.POST("/staking/redeem/early/:id", idempotent, controllers.EarlyRedeemOrderHandler)
+
+	operators := r.Group("/admin", middleware.OperatorAuth())
+	viewers := operators.Group("", middleware.RequireRole(middleware.RoleViewer, middleware.RoleSupport, middleware.RoleTreasurer))
+	viewers.GET("/products/:id/history", controllers.GetProductHistoryHandler)
+
+	support := operators.Group("", middleware.RequireRole(middleware.RoleSupport))
+	support.GET("/audit", controllers.GetAuditLogHandler)
+	support.GET("/audit/verify", controllers.VerifyAuditChainHandler)
+
+	treasurers := operators.Group("", middleware.RequireRole(middleware.RoleTreasurer))
+	treasurers.GET("/ledger/balances", controllers.GetLedgerBalancesHandler)
+	treasurers.GET("/ledger/check", controllers.CheckLedgerHandler)
+	treasurers.GET("/treasury/status", controllers.GetTreasuryStatusHandler)
+	treasurers.GET("/payouts", controllers.GetPayoutsHandler)
+
+	admins := operators.Group("", middleware.RequireRole(middleware.RoleAdmin))
+	admins.POST("/products", idempotent, controllers.CreateProductHandler)
+	admins.PUT("/products/:id", idempotent, controllers.UpdateProductHandler)
+	admins.POST("/products/:id/pause", idempotent, controllers.PauseProductHandler)
+	admins.POST("/products/:id/resume", idempotent, controllers.ResumeProductHandler)
+	admins.POST("/products/:id/retire", idempotent, controllers.RetireProductHandler)
+
+	if _, ok := clock.Fake(); ok {
+		debug := r.Group("/debug", middleware.OperatorAuth(), middleware.RequireRole(middleware.RoleAdmin))
+		debug.GET("/clock", controllers.GetClockHandler)
+		debug.POST("/clock/advance", idempotent, controllers.AdvanceClockHandler)
+	}
+	return r, nil
 }
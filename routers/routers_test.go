@@ -0,0 +1,216 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/spf13/viper"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/controllers"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/middleware"
+	"github.com/metabloxStaking/server"
+	"github.com/metabloxStaking/settings"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	err := controllers.RegisterValidators()
+	if err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// withOperators configures one operator per role, each using its role as its API key
+func withOperators(t *testing.T) {
+	t.Helper()
+	operators := make(map[string]*settings.Operator)
+	for _, role := range []string{middleware.RoleViewer, middleware.RoleSupport, middleware.RoleTreasurer, middleware.RoleAdmin} {
+		operators[role] = &settings.Operator{Role: role, APIKey: role}
+	}
+	settings.Set(&settings.Runtime{Operators: operators})
+	t.Cleanup(func() { settings.Set(&settings.Runtime{}) })
+}
+
+func TestDebugClockRequiresAdmin(t *testing.T) {
+	withOperators(t)
+	clock.Set(clock.NewFakeClock(0))
+	r, err := Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key        string
+		wantStatus int
+	}{
+		{"", http.StatusUnauthorized},
+		{"unknown", http.StatusUnauthorized},
+		{middleware.RoleViewer, http.StatusForbidden},
+		{middleware.RoleSupport, http.StatusForbidden},
+		{middleware.RoleTreasurer, http.StatusForbidden},
+		{middleware.RoleAdmin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run("key "+tt.key, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/debug/clock", nil)
+			if tt.key != "" {
+				req.Header.Set(middleware.OperatorKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestOperatorRoles(t *testing.T) {
+	withOperators(t)
+	//every query fails, so requests that get past the role check end in an error from the handler
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dao.SqlDB = sqlx.NewDb(db, "mysql")
+	r, err := Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewer, support, treasurer, admin := middleware.RoleViewer, middleware.RoleSupport, middleware.RoleTreasurer, middleware.RoleAdmin
+	tests := []struct {
+		method  string
+		path    string
+		allowed []string
+	}{
+		{http.MethodGet, "/admin/products/1/history", []string{viewer, support, treasurer, admin}},
+		{http.MethodGet, "/admin/audit", []string{support, admin}},
+		{http.MethodGet, "/admin/audit/verify", []string{support, admin}},
+		{http.MethodGet, "/admin/ledger/balances", []string{treasurer, admin}},
+		{http.MethodGet, "/admin/ledger/check", []string{treasurer, admin}},
+		{http.MethodGet, "/admin/treasury/status", []string{treasurer, admin}},
+		{http.MethodGet, "/admin/payouts", []string{treasurer, admin}},
+		{http.MethodPost, "/admin/products", []string{admin}},
+		{http.MethodPut, "/admin/products/1", []string{admin}},
+		{http.MethodPost, "/admin/products/1/pause", []string{admin}},
+		{http.MethodPost, "/admin/products/1/resume", []string{admin}},
+		{http.MethodPost, "/admin/products/1/retire", []string{admin}},
+	}
+	for _, tt := range tests {
+		for _, key := range []string{"", "unknown", viewer, support, treasurer, admin} {
+			t.Run(tt.method+" "+tt.path+" key "+key, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				if key != "" {
+					req.Header.Set(middleware.OperatorKeyHeader, key)
+				}
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				switch {
+				case key == "" || key == "unknown":
+					if w.Code != http.StatusUnauthorized {
+						t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+					}
+				case contains(tt.allowed, key):
+					if w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+						t.Errorf("status = %d, want the request let through", w.Code)
+					}
+				default:
+					if w.Code != http.StatusForbidden {
+						t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+					}
+				}
+			})
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTrustedProxies(t *testing.T) {
+	viper.Set("server.trustedProxies", []string{"10.0.0.0/8"})
+	defer viper.Set("server.trustedProxies", nil)
+	r, err := Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.GET("/test/ip", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"direct request", "198.51.100.7", "", "198.51.100.7"},
+		{"forwarded by a trusted proxy", "10.1.2.3", "198.51.100.7", "198.51.100.7"},
+		{"forwarded header from an untrusted client", "198.51.100.7", "203.0.113.9", "198.51.100.7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test/ip", nil)
+			req.RemoteAddr = tt.remote + ":1234"
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Body.String() != tt.want {
+				t.Errorf("client IP = %s, want %s", w.Body.String(), tt.want)
+			}
+		})
+	}
+
+	viper.Set("server.trustedProxies", []string{"not an address"})
+	_, err = Setup()
+	if err == nil {
+		t.Error("Setup() accepted an invalid trusted proxy")
+	}
+}
+
+func TestMetricsListener(t *testing.T) {
+	public, err := Setup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	internal := server.NewMetrics().Handler
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		path       string
+		wantStatus int
+	}{
+		{"metrics are not served publicly", public, "/metrics", http.StatusNotFound},
+		{"metrics on the internal listener", internal, "/metrics", http.StatusOK},
+		{"API is not served on the internal listener", internal, "/product/all", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
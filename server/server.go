@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const defaultAddress = ":8889"
+const defaultMetricsAddress = "127.0.0.1:9090"
+const defaultReadHeaderTimeout = 10 * time.Second
+const defaultReadTimeout = 30 * time.Second
+const defaultWriteTimeout = 60 * time.Second
+const defaultIdleTimeout = 120 * time.Second
+const defaultShutdownTimeout = 30 * time.Second
+
+// New creates the HTTP server from the server section of the config
+func New(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              stringOr("server.address", defaultAddress),
+		Handler:           handler,
+		ReadHeaderTimeout: durationOr("server.readHeaderTimeout", defaultReadHeaderTimeout),
+		ReadTimeout:       durationOr("server.readTimeout", defaultReadTimeout),
+		WriteTimeout:      durationOr("server.writeTimeout", defaultWriteTimeout),
+		IdleTimeout:       durationOr("server.idleTimeout", defaultIdleTimeout),
+	}
+}
+
+// NewMetrics creates the internal server for Prometheus scrapes of /metrics, listening on
+// server.metricsAddress. It is kept apart from the public API so that scrapes need no API key
+// and the endpoint is not reachable by clients.
+func NewMetrics() *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return &http.Server{
+		Addr:              stringOr("server.metricsAddress", defaultMetricsAddress),
+		Handler:           mux,
+		ReadHeaderTimeout: durationOr("server.readHeaderTimeout", defaultReadHeaderTimeout),
+		ReadTimeout:       durationOr("server.readTimeout", defaultReadTimeout),
+		WriteTimeout:      durationOr("server.writeTimeout", defaultWriteTimeout),
+		IdleTimeout:       durationOr("server.idleTimeout", defaultIdleTimeout),
+	}
+}
+
+// Run serves until ctx is cancelled, then stops accepting connections and waits up to
+// server.shutdownTimeout for in-flight requests to finish. TLS is used when both
+// server.tls.certFile and server.tls.keyFile are set.
+func Run(ctx context.Context, srv *http.Server) error {
+	certFile := viper.GetString("server.tls.certFile")
+	keyFile := viper.GetString("server.tls.keyFile")
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("server.tls.certFile and server.tls.keyFile must be set together")
+	}
+
+	return serve(ctx, srv, func() error {
+		if certFile != "" {
+			return srv.ListenAndServeTLS(certFile, keyFile)
+		}
+		return srv.ListenAndServe()
+	})
+}
+
+// RunInternal serves plain HTTP until ctx is cancelled, then shuts down as Run does
+func RunInternal(ctx context.Context, srv *http.Server) error {
+	return serve(ctx, srv, srv.ListenAndServe)
+}
+
+func serve(ctx context.Context, srv *http.Server, listen func() error) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening on " + srv.Addr)
+		serveErr <- listen()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), durationOr("server.shutdownTimeout", defaultShutdownTimeout))
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func stringOr(key, fallback string) string {
+	value := viper.GetString(key)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func durationOr(key string, fallback time.Duration) time.Duration {
+	value := viper.GetDuration(key)
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
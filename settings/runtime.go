@@ -0,0 +1,255 @@
+package settings
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const FeatureTopUp = "topUp"
+const FeatureEarlyRedemption = "earlyRedemption"
+
+const defaultConfirmations = 1
+
+const defaultTimezone = "UTC"
+
+const defaultMaxRateLimitClients = 10000
+
+// Runtime holds every setting read while serving requests or running jobs, applied again whenever
+// the config files change. Readers take the whole snapshot from Current, so they never see half of
+// a change, and nothing reads viper outside of loading. Zero values other than those defaulted in
+// loadRuntime fall back to each package's own default.
+type Runtime struct {
+	LogLevel      logger.Level
+	RateLimit     RateLimit
+	APYOverrides  map[string]float64
+	Features      map[string]bool
+	Confirmations uint64
+	Operators     map[string]*Operator
+	Staking       Staking
+	Pagination    Pagination
+	Contract      Contract
+	Health        Health
+
+	IdempotencyLockTimeout int
+	MinCoverageRatio       float64
+	AuditHashChain         bool
+	ReconcileTimeSlack     time.Duration
+	ReconcileBlockBatch    uint64
+}
+
+// RateLimit is the request rate allowed per client; zero RequestsPerSecond disables limiting.
+// Clients beyond MaxClients share a single limiter.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+	MaxClients        int
+}
+
+// Operator is an operator allowed to use the admin API
+type Operator struct {
+	Role   string
+	APIKey string `mapstructure:"apiKey"`
+}
+
+type Staking struct {
+	// Location is the timezone term boundaries are computed in
+	Location               *time.Location
+	MaxOrderPrincipal      float64
+	EarlyRedemptionPenalty float64
+}
+
+type Pagination struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+type Contract struct {
+	// TokenDecimals is only read at startup
+	TokenDecimals   int64
+	CallTimeout     time.Duration
+	TransferTimeout time.Duration
+}
+
+type Health struct {
+	DBPingTimeout time.Duration
+	MaxBlockAge   time.Duration
+}
+
+// APY returns the override for the product, if one is configured, or fallback otherwise
+func (r *Runtime) APY(productID string, fallback float64) float64 {
+	if apy, ok := r.APYOverrides[productID]; ok {
+		return apy
+	}
+	return fallback
+}
+
+// FeatureEnabled reports whether a feature is switched on. Features are on unless the config
+// turns them off.
+func (r *Runtime) FeatureEnabled(feature string) bool {
+	//viper lower-cases keys
+	enabled, ok := r.Features[strings.ToLower(feature)]
+	return !ok || enabled
+}
+
+var current atomic.Value
+
+func init() {
+	//zero settings until Init or Set runs, so packages used without a config file fall back to their defaults
+	current.Store(&Runtime{})
+}
+
+// Current returns the latest runtime settings
+func Current() *Runtime {
+	return current.Load().(*Runtime)
+}
+
+// Set replaces the runtime settings, for tests and tools that do not load a config file
+func Set(r *Runtime) {
+	current.Store(r)
+}
+
+// staticKeys are read once at startup. A change to one of them is reverted with a warning, so
+// that the running service keeps reporting the values it is actually using.
+var staticKeys = []string{"env", "mysql", "server", "tracing", "log.format", "jobs", "clock", "contract.tokenDecimals"}
+
+var startupValues map[string]interface{}
+
+func loadRuntime() (*Runtime, error) {
+	runtime := &Runtime{}
+
+	level := viper.GetString("log.level")
+	if level == "" {
+		level = "info"
+	}
+	var err error
+	runtime.LogLevel, err = logger.ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	runtime.RateLimit.RequestsPerSecond = viper.GetFloat64("rateLimit.requestsPerSecond")
+	runtime.RateLimit.Burst = viper.GetInt("rateLimit.burst")
+	if runtime.RateLimit.RequestsPerSecond < 0 || runtime.RateLimit.Burst < 0 {
+		return nil, fmt.Errorf("rateLimit values cannot be negative")
+	}
+	if runtime.RateLimit.RequestsPerSecond > 0 && runtime.RateLimit.Burst == 0 {
+		runtime.RateLimit.Burst = 1
+	}
+	runtime.RateLimit.MaxClients = viper.GetInt("rateLimit.maxClients")
+	if runtime.RateLimit.MaxClients <= 0 {
+		runtime.RateLimit.MaxClients = defaultMaxRateLimitClients
+	}
+
+	err = viper.UnmarshalKey("staking.apyOverrides", &runtime.APYOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid staking.apyOverrides: %w", err)
+	}
+	for productID, apy := range runtime.APYOverrides {
+		if apy < 0 {
+			return nil, fmt.Errorf("APY override for product %s cannot be negative", productID)
+		}
+	}
+
+	err = viper.UnmarshalKey("features", &runtime.Features)
+	if err != nil {
+		return nil, fmt.Errorf("invalid features: %w", err)
+	}
+
+	runtime.Confirmations = defaultConfirmations
+	if viper.IsSet("contract.confirmations") {
+		runtime.Confirmations = viper.GetUint64("contract.confirmations")
+	}
+	if runtime.Confirmations == 0 {
+		return nil, fmt.Errorf("contract.confirmations must be at least 1")
+	}
+
+	err = viper.UnmarshalKey("operators", &runtime.Operators)
+	if err != nil {
+		return nil, fmt.Errorf("invalid operators: %w", err)
+	}
+
+	timezone := viper.GetString("staking.timezone")
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	runtime.Staking.Location, err = time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid staking.timezone: %w", err)
+	}
+	runtime.Staking.MaxOrderPrincipal = viper.GetFloat64("staking.maxOrderPrincipal")
+	runtime.Staking.EarlyRedemptionPenalty = viper.GetFloat64("staking.earlyRedemptionPenalty")
+	if runtime.Staking.EarlyRedemptionPenalty < 0 || runtime.Staking.EarlyRedemptionPenalty > 1 {
+		return nil, fmt.Errorf("staking.earlyRedemptionPenalty must be between 0 and 1")
+	}
+
+	runtime.Pagination.DefaultLimit = viper.GetInt("pagination.defaultLimit")
+	runtime.Pagination.MaxLimit = viper.GetInt("pagination.maxLimit")
+
+	runtime.Contract.TokenDecimals = viper.GetInt64("contract.tokenDecimals")
+	runtime.Contract.CallTimeout = viper.GetDuration("contract.callTimeout")
+	runtime.Contract.TransferTimeout = viper.GetDuration("contract.transferTimeout")
+
+	runtime.Health.DBPingTimeout = viper.GetDuration("health.dbPingTimeout")
+	runtime.Health.MaxBlockAge = viper.GetDuration("health.maxBlockAge")
+
+	runtime.IdempotencyLockTimeout = viper.GetInt("idempotency.lockTimeout")
+	runtime.MinCoverageRatio = viper.GetFloat64("treasury.minCoverageRatio")
+	runtime.AuditHashChain = viper.GetBool("audit.hashChain")
+	runtime.ReconcileTimeSlack = viper.GetDuration("reconcile.timeSlack")
+	runtime.ReconcileBlockBatch = viper.GetUint64("reconcile.blockBatch")
+	return runtime, nil
+}
+
+func recordStartupValues() {
+	startupValues = make(map[string]interface{})
+	for _, key := range viper.AllKeys() {
+		if isStatic(key) {
+			startupValues[key] = viper.Get(key)
+		}
+	}
+}
+
+// reload is called after viper has re-read the config file
+func reload() {
+	keys := make(map[string]bool)
+	for _, key := range viper.AllKeys() {
+		keys[key] = isStatic(key)
+	}
+	for key := range startupValues {
+		keys[key] = true
+	}
+	for key, static := range keys {
+		if !static {
+			continue
+		}
+		if old := startupValues[key]; !reflect.DeepEqual(viper.Get(key), old) {
+			logger.Warn("config change to " + key + " cannot be applied while running and has been ignored; restart the service to apply it")
+			viper.Set(key, old)
+		}
+	}
+
+	runtime, err := loadRuntime()
+	if err != nil {
+		logger.Warn("rejected config change, keeping the previous settings: " + err.Error())
+		return
+	}
+	current.Store(runtime)
+	logger.SetLevel(runtime.LogLevel)
+	logger.Info("runtime settings have been reloaded")
+}
+
+func isStatic(key string) bool {
+	for _, static := range staticKeys {
+		static = strings.ToLower(static)
+		if key == static || strings.HasPrefix(key, static+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,25 +1,168 @@
 package settings
 
 import (
-	"runtime"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
-	"github.com/fsnotify/fsnotify"
-	logger "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func Init() (err error) {
-	_, fileName, _, _ := runtime.Caller(0)
-	filePath := fileName[:len(fileName)-20]
-	viper.SetConfigFile(filePath + "/config.yaml")
+// EnvPrefix is the prefix of environment variables that override config keys. A key maps to its
+// upper-cased path with dots replaced by underscores, e.g. METABLOX_MYSQL_PASSWORD for
+// mysql.password. Appending _FILE reads the value from a file instead, for mounted secrets.
+const EnvPrefix = "METABLOX"
 
-	err = viper.ReadInConfig()
+const defaultConfigFile = "config.yaml"
+
+const secretFileSuffix = "_FILE"
+
+var environments = map[string]bool{
+	"dev":     true,
+	"staging": true,
+	"prod":    true,
+}
+
+// requiredKeys must have a non-empty value once every source has been applied
+var requiredKeys = []string{
+	"mysql.host",
+	"mysql.port",
+	"mysql.user",
+	"mysql.dbname",
+}
+
+// secretKeys are required too, but may only be set through an environment variable or a secret
+// file, so that they are never committed with the config files
+var secretKeys = []string{
+	"mysql.password",
+}
+
+// Init loads the configuration. The base file is configPath, or $METABLOX_CONFIG, or config.yaml
+// in the working directory. If env is set to dev, staging or prod, config.<env>.yaml next to the
+// base file is merged over it. Environment variables and secret files override both, and startup
+// fails if a required key is still missing. Changes to either file are picked up while running;
+// see Runtime for what they can change.
+func Init(configPath string) error {
+	if configPath == "" {
+		configPath = os.Getenv(EnvPrefix + "_CONFIG")
+	}
+	if configPath == "" {
+		configPath = defaultConfigFile
+	}
+
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
+	viper.SetConfigFile(configPath)
+	err := viper.ReadInConfig()
+	if err != nil {
+		return err
+	}
+	err = mergeOverlay(configPath)
+	if err != nil {
+		return err
+	}
+	err = applySecretFiles()
+	if err != nil {
+		return err
+	}
+	err = validate()
+	if err != nil {
+		return err
+	}
+	runtime, err := loadRuntime()
+	if err != nil {
+		return err
+	}
+	current.Store(runtime)
+	recordStartupValues()
+
+	return watch(configPath)
+}
+
+// Environment returns the deployment environment selected by the env key, if any
+func Environment() string {
+	return viper.GetString("env")
+}
+
+// overlayPath returns the path of config.<env>.yaml next to the base file, or "" if no
+// environment is selected
+func overlayPath(configPath string) (string, error) {
+	env := Environment()
+	if env == "" {
+		return "", nil
+	}
+	if !environments[env] {
+		return "", fmt.Errorf("unknown environment %q; expected dev, staging or prod", env)
+	}
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + "." + env + ext, nil
+}
+
+func mergeOverlay(configPath string) error {
+	path, err := overlayPath(configPath)
+	if err != nil || path == "" {
+		return err
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	overlay := viper.New()
+	overlay.SetConfigFile(path)
+	err = overlay.ReadInConfig()
 	if err != nil {
 		return err
 	}
-	viper.WatchConfig()
-	viper.OnConfigChange(func(in fsnotify.Event) {
-		logger.Info("config file has been changed")
-	})
+	return viper.MergeConfigMap(overlay.AllSettings())
+}
+
+// applySecretFiles sets each key named by a METABLOX_<KEY>_FILE variable to the contents of the
+// file it points to
+func applySecretFiles() error {
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if !strings.HasPrefix(name, EnvPrefix+"_") || !strings.HasSuffix(name, secretFileSuffix) {
+			continue
+		}
+		path := os.Getenv(name)
+		if path == "" {
+			continue
+		}
+		key := strings.TrimSuffix(strings.TrimPrefix(name, EnvPrefix+"_"), secretFileSuffix)
+		key = strings.ToLower(strings.ReplaceAll(key, "_", "."))
+
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read secret for %s: %w", key, err)
+		}
+		viper.Set(key, strings.TrimRight(string(value), "\r\n"))
+	}
+	return nil
+}
+
+func validate() error {
+	var missing []string
+	for _, key := range requiredKeys {
+		if viper.GetString(key) == "" {
+			missing = append(missing, key)
+		}
+	}
+	for _, key := range secretKeys {
+		variable := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if viper.InConfig(key) {
+			return fmt.Errorf("%s must not be set in a config file; set %s or %s%s instead", key, variable, variable, secretFileSuffix)
+		}
+		if viper.GetString(key) == "" {
+			missing = append(missing, key+" (set "+variable+" or "+variable+secretFileSuffix+")")
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
+	}
 	return nil
 }
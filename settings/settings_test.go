@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidate(t *testing.T) {
+	const base = "mysql:\n  host: db\n  port: 3306\n  user: staking\n  dbname: staking\n"
+	secret := filepath.Join(t.TempDir(), "password")
+	err := ioutil.WriteFile(secret, []byte("from-file\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "password from the environment", config: base, env: map[string]string{"METABLOX_MYSQL_PASSWORD": "secret"}},
+		{name: "password from a secret file", config: base, env: map[string]string{"METABLOX_MYSQL_PASSWORD_FILE": secret}},
+		{name: "password missing", config: base, wantErr: "mysql.password (set METABLOX_MYSQL_PASSWORD or METABLOX_MYSQL_PASSWORD_FILE)"},
+		{
+			name:    "password in the config file",
+			config:  base + "  password: secret\n",
+			env:     map[string]string{"METABLOX_MYSQL_PASSWORD": "secret"},
+			wantErr: "mysql.password must not be set in a config file",
+		},
+		{name: "other key missing", config: "mysql:\n  port: 3306\n  user: staking\n  dbname: staking\n", env: map[string]string{"METABLOX_MYSQL_PASSWORD": "secret"}, wantErr: "mysql.host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for name, value := range tt.env {
+				os.Setenv(name, value)
+				defer os.Unsetenv(name)
+			}
+			viper.SetEnvPrefix(EnvPrefix)
+			viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+			viper.AutomaticEnv()
+			viper.SetConfigType("yaml")
+			err := viper.ReadConfig(bytes.NewBufferString(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = applySecretFiles()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadRuntime(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+		check   func(t *testing.T, r *Runtime)
+	}{
+		{
+			name:   "defaults",
+			config: "log:\n  level: info\n",
+			check: func(t *testing.T, r *Runtime) {
+				if r.Staking.Location != time.UTC {
+					t.Errorf("Location = %v, want UTC", r.Staking.Location)
+				}
+				if r.Confirmations != defaultConfirmations || r.RateLimit.MaxClients != defaultMaxRateLimitClients {
+					t.Errorf("Confirmations = %d, MaxClients = %d", r.Confirmations, r.RateLimit.MaxClients)
+				}
+			},
+		},
+		{
+			name: "values read while serving requests",
+			config: "staking:\n  timezone: Asia/Shanghai\n  earlyRedemptionPenalty: 0.25\n" +
+				"operators:\n  alice:\n    role: admin\n    apiKey: key\n" +
+				"audit:\n  hashChain: true\n" +
+				"contract:\n  callTimeout: 3s\n",
+			check: func(t *testing.T, r *Runtime) {
+				if r.Staking.Location.String() != "Asia/Shanghai" || r.Staking.EarlyRedemptionPenalty != 0.25 {
+					t.Errorf("Staking = %+v", r.Staking)
+				}
+				if alice := r.Operators["alice"]; alice == nil || alice.Role != "admin" || alice.APIKey != "key" {
+					t.Errorf("Operators = %+v", r.Operators)
+				}
+				if !r.AuditHashChain || r.Contract.CallTimeout != 3*time.Second {
+					t.Errorf("AuditHashChain = %v, CallTimeout = %v", r.AuditHashChain, r.Contract.CallTimeout)
+				}
+			},
+		},
+		{name: "unknown timezone", config: "staking:\n  timezone: Nowhere/Special\n", wantErr: "invalid staking.timezone"},
+		{name: "penalty above one", config: "staking:\n  earlyRedemptionPenalty: 1.5\n", wantErr: "earlyRedemptionPenalty"},
+		{name: "negative rate limit", config: "rateLimit:\n  burst: -1\n", wantErr: "rateLimit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			viper.SetConfigType("yaml")
+			err := viper.ReadConfig(bytes.NewBufferString(tt.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			runtime, err := loadRuntime()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("loadRuntime() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, runtime)
+		})
+	}
+}
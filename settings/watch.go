@@ -0,0 +1,97 @@
+package settings
+
+import (
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	logger "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// watch reloads the settings whenever the base config file or its overlay changes. The
+// directories are watched rather than the files, since editors and mounted ConfigMaps replace
+// files instead of writing to them; a file that is replaced through a symlink is noticed by its
+// real path changing. Every change re-reads both files in one goroutine, so a reload always
+// starts from the base file and never sees another reload half done.
+func watch(configPath string) error {
+	overlay, err := overlayPath(configPath)
+	if err != nil {
+		return err
+	}
+	files := []string{filepath.Clean(configPath)}
+	if overlay != "" {
+		files = append(files, filepath.Clean(overlay))
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	realPaths := make(map[string]string)
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		realPaths[file], _ = filepath.EvalSymlinks(file)
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		err = watcher.Add(dir)
+		if err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !configChanged(event, files, realPaths) {
+					continue
+				}
+				err := reread(configPath)
+				if err != nil {
+					logger.Warn("rejected config change, keeping the previous settings: " + err.Error())
+					continue
+				}
+				reload()
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("config watcher failed: " + err.Error())
+			}
+		}
+	}()
+	return nil
+}
+
+// configChanged reports whether the event wrote or created one of the files, or moved the real
+// path one of them links to, updating realPaths
+func configChanged(event fsnotify.Event, files []string, realPaths map[string]string) bool {
+	changed := false
+	for _, file := range files {
+		if filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+			changed = true
+		}
+		realPath, _ := filepath.EvalSymlinks(file)
+		if realPath != "" && realPath != realPaths[file] {
+			realPaths[file] = realPath
+			changed = true
+		}
+	}
+	return changed
+}
+
+// reread loads the base file again and merges the overlay over it
+func reread(configPath string) error {
+	err := viper.ReadInConfig()
+	if err != nil {
+		return err
+	}
+	return mergeOverlay(configPath)
+}
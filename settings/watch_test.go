@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestWatchOverlay(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	os.Setenv("METABLOX_MYSQL_PASSWORD", "secret")
+	defer os.Unsetenv("METABLOX_MYSQL_PASSWORD")
+
+	const mysql = "mysql:\n  host: db\n  port: 3306\n  user: staking\n  dbname: staking\n"
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	overlay := filepath.Join(dir, "config.staging.yaml")
+	write := func(path, content string) {
+		err := ioutil.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(base, "env: staging\n"+mysql+"rateLimit:\n  burst: 1\n")
+	write(overlay, "rateLimit:\n  burst: 2\n")
+
+	err := Init(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if burst := Current().RateLimit.Burst; burst != 2 {
+		t.Fatalf("burst = %d at startup, want the overlay's 2", burst)
+	}
+
+	//each step changes one file and waits for the runtime settings to reflect it
+	tests := []struct {
+		name    string
+		path    string
+		content string
+		want    func(r *Runtime) bool
+	}{
+		{
+			name:    "overlay change",
+			path:    overlay,
+			content: "rateLimit:\n  burst: 3\n",
+			want:    func(r *Runtime) bool { return r.RateLimit.Burst == 3 },
+		},
+		{
+			name:    "base change keeps the overlay applied",
+			path:    base,
+			content: "env: staging\n" + mysql + "rateLimit:\n  burst: 1\n  requestsPerSecond: 5\n",
+			want:    func(r *Runtime) bool { return r.RateLimit.RequestsPerSecond == 5 && r.RateLimit.Burst == 3 },
+		},
+		{
+			name:    "key removed from the overlay falls back to the base file",
+			path:    overlay,
+			content: "features:\n  topUp: false\n",
+			want:    func(r *Runtime) bool { return r.RateLimit.Burst == 1 && !r.FeatureEnabled(FeatureTopUp) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write(tt.path, tt.content)
+			deadline := time.Now().Add(5 * time.Second)
+			for !tt.want(Current()) {
+				if time.Now().After(deadline) {
+					t.Fatalf("settings not reloaded: %+v", Current().RateLimit)
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package terms
+
+import (
+	"time"
+
+	"github.com/metabloxStaking/apperrors"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+// Dates are the boundaries of a single term of an order. The term starts at midnight of the day
+// the order begins, in the configured staking timezone.
+type Dates struct {
+	Start            time.Time
+	LockUpEnd        time.Time
+	Maturity         time.Time
+	ClosureWindowEnd time.Time
+}
+
+// Location returns the timezone that term boundaries are computed in
+func Location() *time.Location {
+	location := settings.Current().Staking.Location
+	if location == nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Compute returns the term boundaries for a term of termDays days with a lock-up of lockUpDays
+// days, starting on the day of the given time. Maturity is the start of the final day of the term,
+// which is also the 24 hour closure window in which the order can be redeemed.
+func Compute(start time.Time, termDays, lockUpDays int) (*Dates, error) {
+	if termDays <= 0 {
+		return nil, apperrors.Conflict("product does not have a term configured")
+	}
+	if lockUpDays < 0 || lockUpDays > termDays {
+		return nil, apperrors.Conflict("product lock-up period must be between zero and the term length")
+	}
+	location := Location()
+
+	local := start.In(location)
+	dates := &Dates{}
+	dates.Start = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	dates.LockUpEnd = dates.Start.AddDate(0, 0, lockUpDays)
+	dates.Maturity = dates.Start.AddDate(0, 0, termDays-1)
+	dates.ClosureWindowEnd = dates.Start.AddDate(0, 0, termDays)
+	return dates, nil
+}
+
+// Apply records the term on an order
+func (d *Dates) Apply(order *models.Order, termDays int) {
+	order.Term = new(int)
+	*order.Term = termDays
+	order.LockUpEndDate = models.NewTimestamp(d.LockUpEnd)
+	order.MaturityDate = models.NewTimestamp(d.Maturity)
+	order.ClosureWindowEnd = models.NewTimestamp(d.ClosureWindowEnd)
+}
@@ -0,0 +1,134 @@
+package terms
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/metabloxStaking/settings"
+)
+
+func TestCompute(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	local := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		start    time.Time
+		term     int
+		lockUp   int
+		want     *Dates
+		wantErr  bool
+	}{
+		{
+			name:  "UTC by default",
+			start: utc(2022, 3, 1, 15),
+			term:  30,
+			want: &Dates{
+				Start:            utc(2022, 3, 1, 0),
+				LockUpEnd:        utc(2022, 3, 1, 0),
+				Maturity:         utc(2022, 3, 30, 0),
+				ClosureWindowEnd: utc(2022, 3, 31, 0),
+			},
+		},
+		{
+			name:   "term crosses a month and a leap day",
+			start:  utc(2024, 2, 20, 23),
+			term:   14,
+			lockUp: 7,
+			want: &Dates{
+				Start:            utc(2024, 2, 20, 0),
+				LockUpEnd:        utc(2024, 2, 27, 0),
+				Maturity:         utc(2024, 3, 4, 0),
+				ClosureWindowEnd: utc(2024, 3, 5, 0),
+			},
+		},
+		{
+			name:     "start is the local day, not the UTC day",
+			location: newYork,
+			start:    utc(2022, 3, 1, 3),
+			term:     2,
+			want: &Dates{
+				Start:            local(2022, 2, 28),
+				LockUpEnd:        local(2022, 2, 28),
+				Maturity:         local(2022, 3, 1),
+				ClosureWindowEnd: local(2022, 3, 2),
+			},
+		},
+		{
+			name:     "clocks go forward during the term",
+			location: newYork,
+			start:    local(2022, 3, 12).Add(15 * time.Hour),
+			term:     3,
+			lockUp:   1,
+			want: &Dates{
+				Start:            local(2022, 3, 12),
+				LockUpEnd:        local(2022, 3, 13),
+				Maturity:         local(2022, 3, 14),
+				ClosureWindowEnd: local(2022, 3, 15),
+			},
+		},
+		{
+			name:     "clocks go back during the term",
+			location: newYork,
+			start:    local(2022, 11, 5).Add(time.Hour),
+			term:     2,
+			want: &Dates{
+				Start:            local(2022, 11, 5),
+				LockUpEnd:        local(2022, 11, 5),
+				Maturity:         local(2022, 11, 6),
+				ClosureWindowEnd: local(2022, 11, 7),
+			},
+		},
+		{
+			name:     "lock-up for the whole term",
+			location: newYork,
+			start:    local(2022, 1, 1),
+			term:     10,
+			lockUp:   10,
+			want: &Dates{
+				Start:            local(2022, 1, 1),
+				LockUpEnd:        local(2022, 1, 11),
+				Maturity:         local(2022, 1, 10),
+				ClosureWindowEnd: local(2022, 1, 11),
+			},
+		},
+		{name: "no term", start: utc(2022, 1, 1, 0), term: 0, wantErr: true},
+		{name: "negative lock-up", start: utc(2022, 1, 1, 0), term: 10, lockUp: -1, wantErr: true},
+		{name: "lock-up longer than the term", start: utc(2022, 1, 1, 0), term: 10, lockUp: 11, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime := &settings.Runtime{}
+			runtime.Staking.Location = tt.location
+			settings.Set(runtime)
+			defer settings.Set(&settings.Runtime{})
+
+			got, err := Compute(tt.start, tt.term, tt.lockUp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			check := func(field string, got, want time.Time) {
+				if !got.Equal(want) {
+					t.Errorf("%s = %v, want %v", field, got, want)
+				}
+			}
+			check("Start", got.Start, tt.want.Start)
+			check("LockUpEnd", got.LockUpEnd, tt.want.LockUpEnd)
+			check("Maturity", got.Maturity, tt.want.Maturity)
+			check("ClosureWindowEnd", got.ClosureWindowEnd, tt.want.ClosureWindowEnd)
+		})
+	}
+}
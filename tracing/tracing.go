@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ExporterOTLP = "otlp"
+const ExporterStdout = "stdout"
+const ExporterNone = "none"
+
+const defaultServiceName = "metabloxStaking"
+const defaultOTLPEndpoint = "localhost:4317"
+
+const tracerName = "github.com/metabloxStaking"
+
+// Init installs the global tracer provider described by the tracing section of the config. Spans
+// go to an OTLP collector over gRPC, or to stdout for local development; with tracing.exporter
+// unset or "none" they are not recorded at all. The returned function flushes any buffered spans
+// and must be called on shutdown.
+func Init() (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch viper.GetString("tracing.exporter") {
+	case ExporterOTLP:
+		endpoint := viper.GetString("tracing.endpoint")
+		if endpoint == "" {
+			endpoint = defaultOTLPEndpoint
+		}
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if viper.GetBool("tracing.insecure") {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(context.Background(), opts...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q; expected otlp, stdout or none", viper.GetString("tracing.exporter"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := 1.0
+	if viper.IsSet("tracing.sampleRatio") {
+		ratio = viper.GetFloat64("tracing.sampleRatio")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(ServiceName()))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// ServiceName is the name spans are reported under
+func ServiceName() string {
+	serviceName := viper.GetString("tracing.serviceName")
+	if serviceName == "" {
+		return defaultServiceName
+	}
+	return serviceName
+}
+
+// Start begins a span as a child of any span carried by ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End records err on the span, if there was one, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
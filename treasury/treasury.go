@@ -0,0 +1,94 @@
+package treasury
+
+import (
+	"context"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+
+	"github.com/metabloxStaking/clock"
+	"github.com/metabloxStaking/contract"
+	"github.com/metabloxStaking/dao"
+	"github.com/metabloxStaking/models"
+	"github.com/metabloxStaking/settings"
+)
+
+const defaultMinCoverageRatio = 1.0
+
+var (
+	mu     sync.RWMutex
+	status = models.NewTreasuryStatus()
+)
+
+// Check compares the contract's token balance with outstanding liabilities and updates the
+// alert state. If either side cannot be read the previous state is kept, so a chain outage
+// neither raises nor clears the alert.
+func Check(ctx context.Context) error {
+	minRatio := settings.Current().MinCoverageRatio
+	if minRatio <= 0 {
+		minRatio = defaultMinCoverageRatio
+	}
+
+	balance, err := contract.TokenBalance(ctx)
+	if err != nil {
+		recordError(err)
+		return err
+	}
+	liabilities, err := dao.GetOutstandingLiabilities(ctx)
+	if err != nil {
+		recordError(err)
+		return err
+	}
+
+	next := models.NewTreasuryStatus()
+	next.Balance = balance
+	next.Liabilities = liabilities
+	next.MinCoverageRatio = minRatio
+	next.CheckedAt = models.NewTimestamp(clock.Now())
+	if liabilities > 0 {
+		next.CoverageRatio = balance / liabilities
+		next.UnderCollateralized = next.CoverageRatio < minRatio
+	}
+
+	mu.Lock()
+	wasUnder := status.UnderCollateralized
+	status = next
+	mu.Unlock()
+
+	if next.UnderCollateralized != wasUnder {
+		entry := logger.WithFields(logger.Fields{
+			"balance":       balance,
+			"liabilities":   liabilities,
+			"coverageRatio": next.CoverageRatio,
+		})
+		if next.UnderCollateralized {
+			entry.Error("treasury is under-collateralized; new orders are blocked and payouts are queued")
+		} else {
+			entry.Info("treasury coverage restored")
+		}
+	}
+	return nil
+}
+
+func recordError(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	updated := *status
+	updated.LastError = err.Error()
+	status = &updated
+}
+
+// Status returns a copy of the latest check
+func Status() *models.TreasuryStatus {
+	mu.RLock()
+	defer mu.RUnlock()
+	current := *status
+	return &current
+}
+
+// UnderCollateralized reports whether the latest check found the treasury below its minimum coverage
+func UnderCollateralized() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return status.UnderCollateralized
+}